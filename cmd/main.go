@@ -6,6 +6,7 @@ import (
 
 	"github.com/freakingeek/fenjoon/internal/database"
 	"github.com/freakingeek/fenjoon/internal/routes"
+	"github.com/freakingeek/fenjoon/internal/workers"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -22,6 +23,20 @@ func main() {
 	database.InitDB()
 	database.InitRedis()
 
+	go workers.RunSubscriptionWorker()
+	go workers.RunStoryScheduler()
+	go workers.RunContestWorker()
+	go workers.RunStoryStatsWorker()
+	go workers.RunWeeklyDigestWorker()
+	go workers.RunBroadcastWorker()
+
+	// Notifications are delivered in-process unless a separate worker command does it
+	if os.Getenv("NOTIFICATION_WORKER") != "external" {
+		go workers.RunNotificationWorker()
+		go workers.RunPushReceiptWorker()
+		go workers.RunDeferredPushWorker()
+	}
+
 	r := gin.Default()
 	gin.SetMode(gin.ReleaseMode)
 
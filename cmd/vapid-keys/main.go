@@ -0,0 +1,21 @@
+// Command vapid-keys generates a VAPID key pair for Web Push. Set the private key as VAPID_PRIVATE_KEY,
+// the public key is derived from it and served to browsers.
+package main
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+)
+
+func main() {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		log.Fatal("Failed to generate key:", err)
+	}
+
+	fmt.Println("VAPID_PRIVATE_KEY=" + base64.RawURLEncoding.EncodeToString(key.Bytes()))
+	fmt.Println("Public key: " + base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()))
+}
@@ -0,0 +1,28 @@
+// Command worker delivers queued notifications outside the API, for deployments setting NOTIFICATION_WORKER=external.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/workers"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if os.Getenv("APP_ENV") != "production" {
+		err := godotenv.Load()
+		if err != nil {
+			log.Fatal("Error loading .env file")
+		}
+	}
+
+	database.InitDB()
+	database.InitRedis()
+
+	log.Println("Notification worker started!")
+	go workers.RunPushReceiptWorker()
+	go workers.RunDeferredPushWorker()
+	workers.RunNotificationWorker()
+}
@@ -12,6 +12,19 @@ func GetUserIdFromContext(c *gin.Context) (uint, error) {
 		return 0, err
 	}
 
+	return getUserIdFromToken(token)
+}
+
+// GetUserIdFromStreamContext also accepts the token in the "token" query parameter, since browsers' EventSource can't set headers.
+func GetUserIdFromStreamContext(c *gin.Context) (uint, error) {
+	if token := c.Query("token"); token != "" && c.GetHeader("Authorization") == "" {
+		return getUserIdFromToken(token)
+	}
+
+	return GetUserIdFromContext(c)
+}
+
+func getUserIdFromToken(token string) (uint, error) {
 	claims, err := ParseJWTToken(token)
 	if err != nil {
 		return 0, err
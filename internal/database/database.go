@@ -17,13 +17,28 @@ func InitDB() {
 		log.Fatal("DATABASE_URL is not set")
 	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	// Translated errors let handlers tell unique violations, gorm.ErrDuplicatedKey, from other failures
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatal("failed to connect database", err)
 	}
 
+	// Push tokens became unique per device. Devices registered more than once keep only their latest row,
+	// and the unique index replaces the plain one.
+	if db.Migrator().HasTable(&models.PushToken{}) && !db.Migrator().HasIndex(&models.PushToken{}, "idx_push_tokens_unique_token") {
+		if err := db.Exec("DELETE FROM push_tokens a USING push_tokens b WHERE a.token = b.token AND a.id < b.id").Error; err != nil {
+			log.Fatal("failed to remove duplicate push tokens", err)
+		}
+
+		if db.Migrator().HasIndex(&models.PushToken{}, "idx_push_tokens_token") {
+			if err := db.Migrator().DropIndex(&models.PushToken{}, "idx_push_tokens_token"); err != nil {
+				log.Fatal("failed to drop the push tokens index", err)
+			}
+		}
+	}
+
 	// Auto-migrate models
-	err = db.AutoMigrate(&models.User{}, &models.Story{}, &models.Like{}, &models.Comment{}, &models.Share{}, &models.PushToken{}, &models.CommentLike{}, &models.Notification{}, &models.StoryReport{}, &models.Follow{}, &models.Bookmark{})
+	err = db.AutoMigrate(&models.User{}, &models.Story{}, &models.Like{}, &models.Comment{}, &models.Share{}, &models.PushToken{}, &models.PushTicket{}, &models.WebPushSubscription{}, &models.CommentLike{}, &models.Notification{}, &models.NotificationSetting{}, &models.NotificationActor{}, &models.NotificationJob{}, &models.Broadcast{}, &models.QuietHours{}, &models.DeferredPush{}, &models.StoryReport{}, &models.Follow{}, &models.Bookmark{}, &models.BookmarkFolder{}, &models.UsernameHistory{}, &models.VerificationRequest{}, &models.VerificationLog{}, &models.Subscription{}, &models.Payment{}, &models.Coupon{}, &models.CouponRedemption{}, &models.Referral{}, &models.StoryRevision{}, &models.Collection{}, &models.CollectionStory{}, &models.CollectionFollow{}, &models.Contest{}, &models.ContestEntry{}, &models.ContestVote{}, &models.UserBadge{}, &models.StoryDailyStat{})
 	if err != nil {
 		log.Fatal("failed to migrate database", err)
 	}
@@ -102,6 +102,14 @@ func GetStoryReport(c *gin.Context) {
 		return
 	}
 
+	reportedText, err := getStoryTextAt(report.StoryID, report.CreatedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	report.ReportedText = reportedText
+
 	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: report})
 }
 
@@ -72,8 +72,9 @@ func SendOTP(c *gin.Context) {
 
 func VerifyOTP(c *gin.Context) {
 	var request struct {
-		Phone string `json:"phone" binding:"required"`
-		Code  string `json:"code" binding:"required"`
+		Phone        string `json:"phone" binding:"required"`
+		Code         string `json:"code" binding:"required"`
+		ReferralCode string `json:"referralCode"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -119,6 +120,8 @@ func VerifyOTP(c *gin.Context) {
 			}
 
 			isNewUser = true
+
+			go rewardReferrer(user, request.ReferralCode)
 		} else {
 			c.JSON(http.StatusInternalServerError, responses.ApiResponse{
 				Status:  http.StatusInternalServerError,
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/freakingeek/fenjoon/internal/auth"
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/messages"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/responses"
+	"github.com/freakingeek/fenjoon/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// findVisibleBookmarkFolder loads a folder, treating non-public folders of other users as missing.
+func findVisibleBookmarkFolder(folderId uint64, userId uint) (models.BookmarkFolder, error) {
+	var folder models.BookmarkFolder
+	if err := database.DB.Preload("User").First(&folder, folderId).Error; err != nil {
+		return folder, err
+	}
+
+	if !folder.IsPublic && folder.UserID != userId {
+		return folder, gorm.ErrRecordNotFound
+	}
+
+	return folder, nil
+}
+
+// bookmarkFolderStories selects the published stories bookmarked in a folder, hiding private stories from everyone but their authors.
+func bookmarkFolderStories(folder models.BookmarkFolder, userId uint) *gorm.DB {
+	return database.DB.Model(&models.Story{}).Scopes(publishedStories).
+		Joins("JOIN bookmarks ON bookmarks.story_id = stories.id").
+		Where("bookmarks.folder_id = ? AND bookmarks.user_id = ? AND bookmarks.deleted_at IS NULL", folder.ID, folder.UserID).
+		Where("stories.is_private = ? OR stories.user_id = ?", false, userId)
+}
+
+// checkBookmarkFolder makes sure a bookmark is being filed into one of the user's own folders. A nil folder is always allowed.
+func checkBookmarkFolder(folderId *uint, userId uint) error {
+	if folderId == nil {
+		return nil
+	}
+
+	return database.DB.Where("id = ? AND user_id = ?", *folderId, userId).First(&models.BookmarkFolder{}).Error
+}
+
+func CreateBookmarkFolder(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var request struct {
+		Title       string `json:"title" binding:"required,min=2,max=100"`
+		Description string `json:"description" binding:"max=512"`
+		IsPublic    bool   `json:"isPublic"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userId).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.UserNotFound, Data: nil})
+		return
+	}
+
+	var foldersCount int64
+	if err := database.DB.Model(&models.BookmarkFolder{}).Where("user_id = ?", userId).Count(&foldersCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	if !services.WithinQuota(services.GetEntitlements(user).MaxBookmarkFolders, foldersCount) {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.BookmarkFolderLimit, Data: nil})
+		return
+	}
+
+	folder := models.BookmarkFolder{UserID: userId, Title: request.Title, Description: request.Description, IsPublic: request.IsPublic}
+
+	if err := database.DB.Create(&folder).Preload("User").First(&folder, folder.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	folder.IsEditableByUser = true
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.BookmarkFolderCreated, Data: folder})
+}
+
+func GetCurrentUserBookmarkFolders(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var folders []models.BookmarkFolder
+	if err := database.DB.Preload("User").Where("user_id = ?", userId).Order("id ASC").Find(&folders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	for i := range folders {
+		var bookmarksCount int64
+		if err := bookmarkFolderStories(folders[i], userId).Count(&bookmarksCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+			return
+		}
+
+		folders[i].BookmarksCount = uint(bookmarksCount)
+		folders[i].IsEditableByUser = true
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: folders})
+}
+
+func UpdateBookmarkFolder(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	folderId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.BookmarkFolderNotFound, Data: nil})
+		return
+	}
+
+	var request struct {
+		Title       string `json:"title" binding:"required,min=2,max=100"`
+		Description string `json:"description" binding:"max=512"`
+		IsPublic    bool   `json:"isPublic"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	var folder models.BookmarkFolder
+	if err := database.DB.Preload("User").Where("id = ? AND user_id = ?", folderId, userId).First(&folder).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.BookmarkFolderNotFound, Data: nil})
+		return
+	}
+
+	folder.Title = request.Title
+	folder.Description = request.Description
+	folder.IsPublic = request.IsPublic
+
+	if err := database.DB.Save(&folder).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	folder.IsEditableByUser = true
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.BookmarkFolderEdited, Data: folder})
+}
+
+func DeleteBookmarkFolder(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	folderId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.BookmarkFolderNotFound, Data: nil})
+		return
+	}
+
+	var folder models.BookmarkFolder
+	if err := database.DB.Where("id = ? AND user_id = ?", folderId, userId).First(&folder).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.BookmarkFolderNotFound, Data: nil})
+		return
+	}
+
+	// The bookmarks themselves are kept, they just no longer belong to a folder
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Bookmark{}).Where("folder_id = ?", folder.ID).Update("folder_id", nil).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&folder).Error
+	})
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.BookmarkFolderDeleted, Data: folder})
+}
+
+func GetReadingList(c *gin.Context) {
+	userId, _ := auth.GetUserIdFromContext(c)
+
+	folderId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.ReadingListNotFound, Data: nil})
+		return
+	}
+
+	folder, err := findVisibleBookmarkFolder(folderId, userId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.ReadingListNotFound, Data: nil})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	var total int64
+	if err := bookmarkFolderStories(folder, userId).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	// Notes are private to the folder's owner, so they aren't part of a reading list
+	var stories []models.Story
+	if err := bookmarkFolderStories(folder, userId).Preload("User").
+		Order("bookmarks.created_at DESC").Limit(limit).Offset(offset).Find(&stories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	folder.BookmarksCount = uint(total)
+	folder.IsEditableByUser = userId == folder.UserID
+
+	c.JSON(http.StatusOK, responses.ApiResponse{
+		Status:  http.StatusOK,
+		Message: messages.GeneralSuccess,
+		Data: map[string]any{
+			"readingList": folder,
+			"stories":     stories,
+			"pagination": map[string]any{
+				"total": total,
+				"page":  page,
+				"limit": limit,
+				"pages": int((total + int64(limit) - 1) / int64(limit)),
+			},
+		},
+	})
+}
+
+func GetUserReadingLists(c *gin.Context) {
+	userId, _ := auth.GetUserIdFromContext(c)
+
+	targetUserId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	var folders []models.BookmarkFolder
+	if err := database.DB.Preload("User").Where("user_id = ? AND is_public = ?", targetUserId, true).Order("id DESC").Find(&folders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	for i := range folders {
+		var bookmarksCount int64
+		if err := bookmarkFolderStories(folders[i], userId).Count(&bookmarksCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+			return
+		}
+
+		folders[i].BookmarksCount = uint(bookmarksCount)
+		folders[i].IsEditableByUser = userId == folders[i].UserID
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: folders})
+}
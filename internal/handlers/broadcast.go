@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/auth"
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/messages"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/responses"
+	"github.com/freakingeek/fenjoon/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateBroadcast schedules an announcement to a segment of users. The broadcast worker queues it in batches from its scheduled time.
+func CreateBroadcast(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userId).Error; err != nil {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	if !user.IsAdmin {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	var request struct {
+		Title         string     `json:"title" binding:"max=100"`
+		Message       string     `json:"message" binding:"required,max=512"`
+		Url           string     `json:"url" binding:"max=256"`
+		InApp         bool       `json:"inApp"`
+		Push          bool       `json:"push"`
+		Segment       string     `json:"segment" binding:"required,oneof=all premium active contest users"`
+		ActiveDays    int        `json:"activeDays" binding:"omitempty,min=1,max=365"`
+		ContestID     *uint      `json:"contestId"`
+		UserIDs       []uint     `json:"userIds" binding:"max=10000"`
+		RatePerMinute int        `json:"ratePerMinute" binding:"omitempty,min=1"`
+		ScheduledAt   *time.Time `json:"scheduledAt"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	if !request.InApp && !request.Push {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.BroadcastChannelRequired, Data: nil})
+		return
+	}
+
+	if (request.Segment == services.BroadcastSegmentActive && request.ActiveDays == 0) ||
+		(request.Segment == services.BroadcastSegmentContest && request.ContestID == nil) ||
+		(request.Segment == services.BroadcastSegmentUsers && len(request.UserIDs) == 0) {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.BroadcastSegmentInvalid, Data: nil})
+		return
+	}
+
+	if request.Segment == services.BroadcastSegmentContest {
+		var contest models.Contest
+		if err := database.DB.First(&contest, *request.ContestID).Error; err != nil {
+			c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.ContestNotFound, Data: nil})
+			return
+		}
+	}
+
+	now := time.Now()
+	scheduledAt := now
+	if request.ScheduledAt != nil {
+		if request.ScheduledAt.Before(now.Add(-time.Minute)) {
+			c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.BroadcastScheduleInvalid, Data: nil})
+			return
+		}
+
+		scheduledAt = *request.ScheduledAt
+	}
+
+	ratePerMinute := request.RatePerMinute
+	if ratePerMinute == 0 {
+		ratePerMinute = services.DefaultBroadcastRatePerMinute
+	}
+
+	broadcast := models.Broadcast{
+		Title:         request.Title,
+		Message:       request.Message,
+		Url:           request.Url,
+		InApp:         request.InApp,
+		Push:          request.Push,
+		Segment:       request.Segment,
+		RatePerMinute: min(ratePerMinute, services.MaxBroadcastRatePerMinute),
+		Status:        "scheduled",
+		ScheduledAt:   scheduledAt,
+		CreatedBy:     userId,
+	}
+
+	// Only the options of the chosen segment are kept
+	switch request.Segment {
+	case services.BroadcastSegmentActive:
+		broadcast.ActiveDays = request.ActiveDays
+	case services.BroadcastSegmentContest:
+		broadcast.ContestID = request.ContestID
+	case services.BroadcastSegmentUsers:
+		broadcast.UserIDs = request.UserIDs
+	}
+
+	var audience int64
+	if err := services.BroadcastRecipients(database.DB, broadcast).Count(&audience).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	if err := database.DB.Create(&broadcast).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.BroadcastCreated, Data: map[string]any{
+		"broadcast": broadcast,
+		"audience":  audience,
+	}})
+}
+
+func GetBroadcasts(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userId).Error; err != nil {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	if !user.IsAdmin {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	query := database.DB.Model(&models.Broadcast{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	var broadcasts []models.Broadcast
+	if err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&broadcasts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{
+		Status:  http.StatusOK,
+		Message: messages.GeneralSuccess,
+		Data: map[string]any{
+			"broadcasts": broadcasts,
+			"pagination": map[string]any{
+				"total": total,
+				"page":  page,
+				"limit": limit,
+				"pages": int((total + int64(limit) - 1) / int64(limit)),
+			},
+		},
+	})
+}
+
+// GetBroadcast returns the broadcast with its delivery report.
+func GetBroadcast(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userId).Error; err != nil {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	if !user.IsAdmin {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	var broadcast models.Broadcast
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&broadcast).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.BroadcastNotFound, Data: nil})
+		return
+	}
+
+	report, err := services.GetBroadcastReport(broadcast.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	broadcast.Report = report
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: broadcast})
+}
+
+// CancelBroadcast stops a broadcast that hasn't finished queueing, and drops its jobs not delivered yet.
+func CancelBroadcast(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userId).Error; err != nil {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	if !user.IsAdmin {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	var broadcast models.Broadcast
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&broadcast).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.BroadcastNotFound, Data: nil})
+		return
+	}
+
+	errNotCancellable := errors.New("broadcast is not cancellable")
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&broadcast).Where("status IN ?", []string{"scheduled", "sending"}).Update("status", "cancelled")
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return errNotCancellable
+		}
+
+		return tx.Where("broadcast_id = ? AND status = ?", broadcast.ID, "pending").Delete(&models.NotificationJob{}).Error
+	})
+
+	if errors.Is(err, errNotCancellable) {
+		c.JSON(http.StatusConflict, responses.ApiResponse{Status: http.StatusConflict, Message: messages.BroadcastNotCancellable, Data: nil})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	broadcast.Status = "cancelled"
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.BroadcastCancelled, Data: broadcast})
+}
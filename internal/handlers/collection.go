@@ -0,0 +1,578 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/freakingeek/fenjoon/internal/auth"
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/messages"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/responses"
+	"github.com/freakingeek/fenjoon/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// findVisibleCollection loads a collection, treating private collections of other users as missing.
+func findVisibleCollection(collectionId uint64, userId uint) (models.Collection, error) {
+	var collection models.Collection
+	if err := database.DB.Preload("User").First(&collection, collectionId).Error; err != nil {
+		return collection, err
+	}
+
+	if collection.IsPrivate && collection.UserID != userId {
+		return collection, gorm.ErrRecordNotFound
+	}
+
+	return collection, nil
+}
+
+// collectionStories selects the published stories of a collection, in order, hiding private ones from everyone but the author.
+func collectionStories(collection models.Collection, userId uint) *gorm.DB {
+	query := database.DB.Model(&models.Story{}).Scopes(publishedStories).
+		Joins("JOIN collection_stories ON collection_stories.story_id = stories.id").
+		Where("collection_stories.collection_id = ?", collection.ID)
+
+	if collection.UserID != userId {
+		query = query.Where("stories.is_private = ?", false)
+	}
+
+	return query
+}
+
+func getStorySeries(story models.Story, userId uint) (*models.StorySeries, error) {
+	var entry models.CollectionStory
+	if err := database.DB.Where("story_id = ?", story.ID).First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	collection, err := findVisibleCollection(uint64(entry.CollectionID), userId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	series := models.StorySeries{CollectionID: collection.ID, Title: collection.Title}
+
+	var previous []uint
+	if err := collectionStories(collection, userId).Where("collection_stories.position < ?", entry.Position).
+		Order("collection_stories.position DESC").Limit(1).Pluck("stories.id", &previous).Error; err != nil {
+		return nil, err
+	}
+
+	var next []uint
+	if err := collectionStories(collection, userId).Where("collection_stories.position > ?", entry.Position).
+		Order("collection_stories.position ASC").Limit(1).Pluck("stories.id", &next).Error; err != nil {
+		return nil, err
+	}
+
+	if len(previous) > 0 {
+		series.PreviousStoryID = &previous[0]
+	}
+
+	if len(next) > 0 {
+		series.NextStoryID = &next[0]
+	}
+
+	return &series, nil
+}
+
+func notifyCollectionFollowers(collection models.Collection, story models.Story) {
+	if collection.IsPrivate || story.IsPrivate {
+		return
+	}
+
+	var follows []models.CollectionFollow
+	if err := database.DB.Where("collection_id = ? AND user_id <> ?", collection.ID, collection.UserID).Find(&follows).Error; err != nil {
+		fmt.Printf("Failed to find collection followers: %v\n", err)
+		return
+	}
+
+	for _, follow := range follows {
+		services.NotifyUser(models.Notification{
+			UserID:     follow.UserID,
+			Type:       services.NotificationCollectionUpdated,
+			ActorID:    &collection.UserID,
+			TargetType: "story",
+			TargetID:   &story.ID,
+			Title:      "قسمت تازه منتشر شد!",
+			Message:    fmt.Sprintf("قسمت تازه‌ای به مجموعه «%s» اضافه شد", collection.Title),
+			Url:        fmt.Sprintf("/story/%d", story.ID),
+		})
+	}
+}
+
+func CreateCollection(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var request struct {
+		Title       string `json:"title" binding:"required,min=2,max=100"`
+		Description string `json:"description" binding:"max=512"`
+		IsPrivate   bool   `json:"isPrivate"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	collection := models.Collection{UserID: userId, Title: request.Title, Description: request.Description, IsPrivate: request.IsPrivate}
+
+	if err := database.DB.Create(&collection).Preload("User").First(&collection, collection.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	collection.IsEditableByUser = true
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.CollectionCreated, Data: collection})
+}
+
+func GetCollectionById(c *gin.Context) {
+	userId, _ := auth.GetUserIdFromContext(c)
+
+	collectionId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.CollectionNotFound, Data: nil})
+		return
+	}
+
+	collection, err := findVisibleCollection(collectionId, userId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.CollectionNotFound, Data: nil})
+		return
+	}
+
+	var stories []models.Story
+	if err := collectionStories(collection, userId).Preload("User").Order("collection_stories.position ASC").Find(&stories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	var followersCount int64
+	if err := database.DB.Model(&models.CollectionFollow{}).Where("collection_id = ?", collection.ID).Count(&followersCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	var isFollowedByUser bool
+	if err := database.DB.Model(&models.CollectionFollow{}).
+		Where("collection_id = ? AND user_id = ?", collection.ID, userId).
+		Select("COUNT(*) > 0").
+		Find(&isFollowedByUser).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	collection.StoriesCount = uint(len(stories))
+	collection.FollowersCount = uint(followersCount)
+	collection.IsFollowedByUser = isFollowedByUser
+	collection.IsEditableByUser = userId == collection.UserID
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: map[string]any{
+		"collection": collection,
+		"stories":    stories,
+	}})
+}
+
+func GetUserCollections(c *gin.Context) {
+	userId, _ := auth.GetUserIdFromContext(c)
+
+	targetUserId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	query := database.DB.Model(&models.Collection{}).Where("user_id = ?", targetUserId)
+	if uint(targetUserId) != userId {
+		query = query.Where("is_private = ?", false)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	var collections []models.Collection
+	if err := query.Preload("User").Order("id DESC").Limit(limit).Offset(offset).Find(&collections).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	for i := range collections {
+		var storiesCount int64
+		if err := collectionStories(collections[i], userId).Count(&storiesCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+			return
+		}
+
+		collections[i].StoriesCount = uint(storiesCount)
+		collections[i].IsEditableByUser = userId == collections[i].UserID
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{
+		Status:  http.StatusOK,
+		Message: messages.GeneralSuccess,
+		Data: map[string]any{
+			"collections": collections,
+			"pagination": map[string]any{
+				"total": total,
+				"page":  page,
+				"limit": limit,
+				"pages": int((total + int64(limit) - 1) / int64(limit)),
+			},
+		},
+	})
+}
+
+func UpdateCollection(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	collectionId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.CollectionNotFound, Data: nil})
+		return
+	}
+
+	var request struct {
+		Title       string `json:"title" binding:"required,min=2,max=100"`
+		Description string `json:"description" binding:"max=512"`
+		IsPrivate   bool   `json:"isPrivate"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	var collection models.Collection
+	if err := database.DB.Preload("User").Where("id = ? AND user_id = ?", collectionId, userId).First(&collection).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.CollectionNotFound, Data: nil})
+		return
+	}
+
+	collection.Title = request.Title
+	collection.Description = request.Description
+	collection.IsPrivate = request.IsPrivate
+
+	if err := database.DB.Save(&collection).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	collection.IsEditableByUser = true
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.CollectionEdited, Data: collection})
+}
+
+func DeleteCollection(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	collectionId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.CollectionNotFound, Data: nil})
+		return
+	}
+
+	var collection models.Collection
+	if err := database.DB.Where("id = ? AND user_id = ?", collectionId, userId).First(&collection).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.CollectionNotFound, Data: nil})
+		return
+	}
+
+	// The stories themselves are kept, they just no longer belong to a series
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("collection_id = ?", collection.ID).Delete(&models.CollectionStory{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("collection_id = ?", collection.ID).Delete(&models.CollectionFollow{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&collection).Error
+	})
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.CollectionDeleted, Data: collection})
+}
+
+func AddStoryToCollection(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	collectionId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.CollectionNotFound, Data: nil})
+		return
+	}
+
+	var request struct {
+		StoryID uint `json:"storyId" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	var collection models.Collection
+	if err := database.DB.Where("id = ? AND user_id = ?", collectionId, userId).First(&collection).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.CollectionNotFound, Data: nil})
+		return
+	}
+
+	var story models.Story
+	if err := database.DB.Scopes(publishedStories).Where("id = ? AND user_id = ?", request.StoryID, userId).First(&story).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
+		return
+	}
+
+	var entry models.CollectionStory
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the collection so concurrent additions don't get the same position
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Collection{}, collection.ID).Error; err != nil {
+			return err
+		}
+
+		var lastPosition int
+		if err := tx.Model(&models.CollectionStory{}).Where("collection_id = ?", collection.ID).
+			Select("COALESCE(MAX(position), 0)").Scan(&lastPosition).Error; err != nil {
+			return err
+		}
+
+		entry = models.CollectionStory{CollectionID: collection.ID, StoryID: story.ID, Position: lastPosition + 1}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return gorm.ErrDuplicatedKey
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		c.JSON(http.StatusConflict, responses.ApiResponse{Status: http.StatusConflict, Message: messages.CollectionStoryTaken, Data: nil})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	notifyCollectionFollowers(collection, story)
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.CollectionStoryAdded, Data: entry})
+}
+
+func RemoveStoryFromCollection(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	collectionId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.CollectionNotFound, Data: nil})
+		return
+	}
+
+	storyId, err := strconv.ParseUint(c.Param("storyId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.StoryNotFound, Data: nil})
+		return
+	}
+
+	var collection models.Collection
+	if err := database.DB.Where("id = ? AND user_id = ?", collectionId, userId).First(&collection).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.CollectionNotFound, Data: nil})
+		return
+	}
+
+	result := database.DB.Where("collection_id = ? AND story_id = ?", collection.ID, storyId).Delete(&models.CollectionStory{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.CollectionStoryRemoved, Data: nil})
+}
+
+func ReorderCollectionStories(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	collectionId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.CollectionNotFound, Data: nil})
+		return
+	}
+
+	var request struct {
+		StoryIDs []uint `json:"storyIds" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	var collection models.Collection
+	if err := database.DB.Where("id = ? AND user_id = ?", collectionId, userId).First(&collection).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.CollectionNotFound, Data: nil})
+		return
+	}
+
+	var entries []models.CollectionStory
+	if err := database.DB.Where("collection_id = ?", collection.ID).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	positions := make(map[uint]int, len(request.StoryIDs))
+	for i, storyId := range request.StoryIDs {
+		positions[storyId] = i + 1
+	}
+
+	if len(positions) != len(request.StoryIDs) || len(positions) != len(entries) {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.CollectionOrderInvalid, Data: nil})
+		return
+	}
+
+	for _, entry := range entries {
+		if _, ok := positions[entry.StoryID]; !ok {
+			c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.CollectionOrderInvalid, Data: nil})
+			return
+		}
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, entry := range entries {
+			if err := tx.Model(&entry).Update("position", positions[entry.StoryID]).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.CollectionEdited, Data: request.StoryIDs})
+}
+
+func FollowCollection(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	collectionId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.CollectionNotFound, Data: nil})
+		return
+	}
+
+	collection, err := findVisibleCollection(collectionId, userId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.CollectionNotFound, Data: nil})
+		return
+	}
+
+	follow := models.CollectionFollow{CollectionID: collection.ID, UserID: userId}
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, responses.ApiResponse{Status: http.StatusConflict, Message: messages.CollectionAlreadyFollowed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.CollectionFollowed, Data: nil})
+}
+
+func UnfollowCollection(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	collectionId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.CollectionNotFound, Data: nil})
+		return
+	}
+
+	if err := database.DB.Where("collection_id = ? AND user_id = ?", collectionId, userId).Delete(&models.CollectionFollow{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.CollectionUnfollowed, Data: nil})
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/freakingeek/fenjoon/internal/services"
 	"github.com/freakingeek/fenjoon/internal/utils"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func GetCommentById(c *gin.Context) {
@@ -150,25 +151,30 @@ func LikeCommentById(c *gin.Context) {
 	}
 
 	commentLike := models.CommentLike{CommentID: uint(commentId), UserID: userId}
-	if err := database.DB.Create(&commentLike).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
-		return
-	}
-
-	if userId != comment.UserID {
-		text := fmt.Sprintf("%s از نقدت خوشش اومد", utils.GetUserDisplayName(user))
-
-		notification := models.Notification{UserID: comment.UserID, Title: "نقدت پسندیده شد!", Message: text, Url: fmt.Sprintf("/story/%d", comment.StoryID)}
-		if err := services.SendInAppNotification(notification); err != nil {
-			fmt.Printf("Failed to send in-app notification: %v\n", err)
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&commentLike).Error; err != nil {
+			return err
 		}
 
-		var pushToken models.PushToken
-		if err := database.DB.Where("user_id = ?", comment.UserID).First(&pushToken).Error; err == nil {
-			if err := services.SendPushNotification([]string{pushToken.Token}, text); err != nil {
-				fmt.Printf("Failed to send push notification: %v\n", err)
-			}
+		if userId == comment.UserID {
+			return nil
 		}
+
+		return services.EnqueueNotification(tx, models.Notification{
+			UserID:     comment.UserID,
+			Type:       services.NotificationCommentLiked,
+			ActorID:    &userId,
+			TargetType: "comment",
+			TargetID:   &comment.ID,
+			Title:      "نقدت پسندیده شد!",
+			Message:    fmt.Sprintf("%s از نقدت خوشش اومد", utils.GetUserDisplayName(user)),
+			Url:        fmt.Sprintf("/story/%d", comment.StoryID),
+		})
+	})
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
 	}
 
 	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.CommentLiked, Data: true})
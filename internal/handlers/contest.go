@@ -0,0 +1,511 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/auth"
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/messages"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/responses"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// contestVoterMinAge keeps freshly made accounts from voting, which makes ballot stuffing with throwaway accounts harder.
+const contestVoterMinAge = 3 * 24 * time.Hour
+
+type contestRequest struct {
+	Type             string     `json:"type" binding:"required,oneof=prompt contest"`
+	Title            string     `json:"title" binding:"required,min=3,max=100"`
+	Theme            string     `json:"theme" binding:"required,min=3,max=512"`
+	Rules            string     `json:"rules" binding:"max=5000"`
+	StartsAt         time.Time  `json:"startsAt" binding:"required"`
+	SubmissionsEndAt time.Time  `json:"submissionsEndAt" binding:"required"`
+	VotingEndsAt     *time.Time `json:"votingEndsAt"`
+	WinnersCount     int        `json:"winnersCount" binding:"min=0,max=10"`
+}
+
+func isValidContestRequest(request contestRequest) bool {
+	if !request.StartsAt.Before(request.SubmissionsEndAt) {
+		return false
+	}
+
+	if request.Type == "prompt" {
+		return request.VotingEndsAt == nil
+	}
+
+	return request.VotingEndsAt != nil && request.SubmissionsEndAt.Before(*request.VotingEndsAt)
+}
+
+func getContestPhase(contest models.Contest, now time.Time) string {
+	switch {
+	case now.Before(contest.StartsAt):
+		return "upcoming"
+	case now.Before(contest.SubmissionsEndAt):
+		return "submissions"
+	case contest.VotingEndsAt != nil && now.Before(*contest.VotingEndsAt):
+		return "voting"
+	default:
+		return "finished"
+	}
+}
+
+// visibleContestEntries hides entries whose story was deleted or made private.
+func visibleContestEntries(db *gorm.DB) *gorm.DB {
+	return db.Where("contest_entries.story_id IN (?)", database.DB.Model(&models.Story{}).Scopes(publishedStories).Where("is_private = ?", false).Select("id"))
+}
+
+func CreateContest(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	if !user.IsAdmin {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	var request contestRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	if !isValidContestRequest(request) {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.ContestInvalidDates, Data: nil})
+		return
+	}
+
+	contest := models.Contest{
+		Type:             request.Type,
+		Title:            request.Title,
+		Theme:            request.Theme,
+		Rules:            request.Rules,
+		StartsAt:         request.StartsAt,
+		SubmissionsEndAt: request.SubmissionsEndAt,
+		VotingEndsAt:     request.VotingEndsAt,
+		WinnersCount:     request.WinnersCount,
+		CreatedBy:        userId,
+	}
+
+	if err := database.DB.Create(&contest).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	contest.Phase = getContestPhase(contest, time.Now())
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: contest})
+}
+
+func UpdateContest(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	if !user.IsAdmin {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	contestId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.ContestNotFound, Data: nil})
+		return
+	}
+
+	var request contestRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	if !isValidContestRequest(request) {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.ContestInvalidDates, Data: nil})
+		return
+	}
+
+	var contest models.Contest
+	if err := database.DB.First(&contest, contestId).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.ContestNotFound, Data: nil})
+		return
+	}
+
+	contest.Type = request.Type
+	contest.Title = request.Title
+	contest.Theme = request.Theme
+	contest.Rules = request.Rules
+	contest.StartsAt = request.StartsAt
+	contest.SubmissionsEndAt = request.SubmissionsEndAt
+	contest.VotingEndsAt = request.VotingEndsAt
+	contest.WinnersCount = request.WinnersCount
+
+	if err := database.DB.Save(&contest).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	contest.Phase = getContestPhase(contest, time.Now())
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: contest})
+}
+
+func DeleteContest(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	if !user.IsAdmin {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	contestId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.ContestNotFound, Data: nil})
+		return
+	}
+
+	var contest models.Contest
+	if err := database.DB.First(&contest, contestId).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.ContestNotFound, Data: nil})
+		return
+	}
+
+	if err := database.DB.Delete(&contest).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: contest})
+}
+
+func GetContests(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	now := time.Now()
+	query := database.DB.Model(&models.Contest{})
+
+	switch c.Query("phase") {
+	case "upcoming":
+		query = query.Where("starts_at > ?", now)
+	case "active":
+		query = query.Where("starts_at <= ? AND COALESCE(voting_ends_at, submissions_end_at) > ?", now, now)
+	case "finished":
+		query = query.Where("COALESCE(voting_ends_at, submissions_end_at) <= ?", now)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	var contests []models.Contest
+	if err := query.Order("starts_at DESC").Limit(limit).Offset(offset).Find(&contests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	for i := range contests {
+		contests[i].Phase = getContestPhase(contests[i], now)
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{
+		Status:  http.StatusOK,
+		Message: messages.GeneralSuccess,
+		Data: map[string]any{
+			"contests": contests,
+			"pagination": map[string]any{
+				"total": total,
+				"page":  page,
+				"limit": limit,
+				"pages": int((total + int64(limit) - 1) / int64(limit)),
+			},
+		},
+	})
+}
+
+func GetContestById(c *gin.Context) {
+	contestId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.ContestNotFound, Data: nil})
+		return
+	}
+
+	var contest models.Contest
+	if err := database.DB.First(&contest, contestId).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.ContestNotFound, Data: nil})
+		return
+	}
+
+	var entriesCount int64
+	if err := database.DB.Model(&models.ContestEntry{}).Scopes(visibleContestEntries).Where("contest_id = ?", contest.ID).Count(&entriesCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	contest.Phase = getContestPhase(contest, time.Now())
+	contest.EntriesCount = uint(entriesCount)
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: contest})
+}
+
+func GetContestEntries(c *gin.Context) {
+	userId, _ := auth.GetUserIdFromContext(c)
+
+	contestId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.ContestNotFound, Data: nil})
+		return
+	}
+
+	var contest models.Contest
+	if err := database.DB.First(&contest, contestId).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.ContestNotFound, Data: nil})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	query := database.DB.Model(&models.ContestEntry{}).Scopes(visibleContestEntries).Where("contest_id = ?", contest.ID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	// Vote counts stay hidden until the results are in, so the ranking is shown only then
+	order := "id ASC"
+	if contest.ResultsAnnouncedAt != nil {
+		order = "contest_entries.rank = 0, contest_entries.rank ASC, votes_count DESC, id ASC"
+	}
+
+	var entries []models.ContestEntry
+	if err := query.Preload("Story.User").Order(order).Limit(limit).Offset(offset).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	var votedEntryId uint
+	if err := database.DB.Model(&models.ContestVote{}).Where("contest_id = ? AND user_id = ?", contest.ID, userId).Select("entry_id").Limit(1).Find(&votedEntryId).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	for i := range entries {
+		entries[i].IsVotedByUser = entries[i].ID == votedEntryId
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{
+		Status:  http.StatusOK,
+		Message: messages.GeneralSuccess,
+		Data: map[string]any{
+			"entries": entries,
+			"pagination": map[string]any{
+				"total": total,
+				"page":  page,
+				"limit": limit,
+				"pages": int((total + int64(limit) - 1) / int64(limit)),
+			},
+		},
+	})
+}
+
+func SubmitContestEntry(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	contestId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.ContestNotFound, Data: nil})
+		return
+	}
+
+	var request struct {
+		StoryID uint `json:"storyId" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	var contest models.Contest
+	if err := database.DB.First(&contest, contestId).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.ContestNotFound, Data: nil})
+		return
+	}
+
+	if getContestPhase(contest, time.Now()) != "submissions" {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.ContestSubmissionsClosed, Data: nil})
+		return
+	}
+
+	var story models.Story
+	if err := database.DB.Preload("User").Scopes(publishedStories).Where("id = ? AND user_id = ? AND is_private = ?", request.StoryID, userId, false).First(&story).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
+		return
+	}
+
+	publishedAt := story.CreatedAt
+	if story.PublishedAt != nil {
+		publishedAt = *story.PublishedAt
+	}
+
+	if publishedAt.Before(contest.StartsAt) {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.ContestStoryTooOld, Data: nil})
+		return
+	}
+
+	// Prompts take any number of responses, contests one story per writer
+	if contest.Type == "contest" {
+		var entriesCount int64
+		if err := database.DB.Model(&models.ContestEntry{}).Where("contest_id = ? AND user_id = ?", contest.ID, userId).Count(&entriesCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+			return
+		}
+
+		if entriesCount > 0 {
+			c.JSON(http.StatusConflict, responses.ApiResponse{Status: http.StatusConflict, Message: messages.ContestEntryLimit, Data: nil})
+			return
+		}
+	}
+
+	entry := models.ContestEntry{ContestID: contest.ID, StoryID: story.ID, UserID: userId}
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, responses.ApiResponse{Status: http.StatusConflict, Message: messages.ContestEntryTaken, Data: nil})
+		return
+	}
+
+	entry.Story = story
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.ContestEntrySubmitted, Data: entry})
+}
+
+func VoteContestEntry(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	contestId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.ContestNotFound, Data: nil})
+		return
+	}
+
+	entryId, err := strconv.ParseUint(c.Param("entryId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.ContestEntryNotFound, Data: nil})
+		return
+	}
+
+	var contest models.Contest
+	if err := database.DB.First(&contest, contestId).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.ContestNotFound, Data: nil})
+		return
+	}
+
+	if getContestPhase(contest, time.Now()) != "voting" {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.ContestVotingClosed, Data: nil})
+		return
+	}
+
+	var entry models.ContestEntry
+	if err := database.DB.Scopes(visibleContestEntries).Where("id = ? AND contest_id = ?", entryId, contest.ID).First(&entry).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.ContestEntryNotFound, Data: nil})
+		return
+	}
+
+	if entry.UserID == userId {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.ContestVoteOwnEntry, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userId).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.UserNotFound, Data: nil})
+		return
+	}
+
+	if time.Since(user.CreatedAt) < contestVoterMinAge {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.ContestVoterTooNew, Data: nil})
+		return
+	}
+
+	vote := models.ContestVote{ContestID: contest.ID, UserID: userId, EntryID: entry.ID}
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&vote)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, responses.ApiResponse{Status: http.StatusConflict, Message: messages.ContestAlreadyVoted, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.ContestVoted, Data: true})
+}
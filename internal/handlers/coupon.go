@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/auth"
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/messages"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/responses"
+	"github.com/freakingeek/fenjoon/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errCouponUsedUp = errors.New("coupon usage limit reached")
+
+func findRedeemableCoupon(code string, userId uint) (models.Coupon, int, string) {
+	var coupon models.Coupon
+	if err := database.DB.Where("code = ?", strings.ToUpper(strings.TrimSpace(code))).First(&coupon).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return coupon, http.StatusNotFound, messages.CouponNotFound
+		}
+
+		return coupon, http.StatusInternalServerError, messages.GeneralFailed
+	}
+
+	if coupon.ExpiresAt != nil && coupon.ExpiresAt.Before(time.Now()) {
+		return coupon, http.StatusGone, messages.CouponExpired
+	}
+
+	if coupon.MaxUses > 0 && coupon.UsedCount >= coupon.MaxUses {
+		return coupon, http.StatusGone, messages.CouponUsageLimit
+	}
+
+	var redemptionsCount int64
+	if err := database.DB.Model(&models.CouponRedemption{}).Where("coupon_id = ? AND user_id = ?", coupon.ID, userId).Count(&redemptionsCount).Error; err != nil {
+		return coupon, http.StatusInternalServerError, messages.GeneralFailed
+	}
+
+	if redemptionsCount > 0 {
+		return coupon, http.StatusConflict, messages.CouponAlreadyUsed
+	}
+
+	return coupon, http.StatusOK, messages.GeneralSuccess
+}
+
+func applyCouponDiscount(price int64, coupon models.Coupon) int64 {
+	var discount int64
+
+	switch coupon.Type {
+	case "percent":
+		discount = price * coupon.Value / 100
+	case "fixed":
+		discount = coupon.Value
+	}
+
+	return min(max(discount, 0), price)
+}
+
+// redeemCoupon consumes one use of the coupon, failing if it ran out or the user redeemed it since it was validated.
+// Purchases reserve their use together with the payment, so a coupon is never paid for more times than it allows;
+// services.FailPayment gives the use back if the payment doesn't go through.
+func redeemCoupon(tx *gorm.DB, couponId uint, userId uint, paymentId uint) error {
+	result := tx.Model(&models.Coupon{}).
+		Where("id = ? AND (max_uses = 0 OR used_count < max_uses)", couponId).
+		Update("used_count", gorm.Expr("used_count + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errCouponUsedUp
+	}
+
+	return tx.Create(&models.CouponRedemption{CouponID: couponId, UserID: userId, PaymentID: paymentId}).Error
+}
+
+// couponRedemptionFailure picks the response for a redeemCoupon error.
+func couponRedemptionFailure(err error) (int, string) {
+	switch {
+	case errors.Is(err, errCouponUsedUp):
+		return http.StatusGone, messages.CouponUsageLimit
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return http.StatusConflict, messages.CouponAlreadyUsed
+	default:
+		return http.StatusInternalServerError, messages.GeneralFailed
+	}
+}
+
+func RedeemCoupon(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var request struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	coupon, status, message := findRedeemableCoupon(request.Code, userId)
+	if status != http.StatusOK {
+		c.JSON(status, responses.ApiResponse{Status: status, Message: message, Data: nil})
+		return
+	}
+
+	// Discount coupons are applied while buying a plan, only free days can be redeemed directly
+	if coupon.Type != "free_days" {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.CouponNotApplicable, Data: nil})
+		return
+	}
+
+	var subscription models.Subscription
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := redeemCoupon(tx, coupon.ID, userId, 0); err != nil {
+			return err
+		}
+
+		granted, err := services.GrantPremium(tx, userId, "coupon", time.Duration(coupon.Value)*24*time.Hour, 0)
+		subscription = granted
+		return err
+	})
+
+	if err != nil {
+		status, message := couponRedemptionFailure(err)
+		c.JSON(status, responses.ApiResponse{Status: status, Message: message, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.CouponRedeemed, Data: subscription})
+}
+
+func CreateCoupon(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	if !user.IsAdmin {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	var request struct {
+		Code      string     `json:"code" binding:"required,alphanum,min=4,max=32"`
+		Type      string     `json:"type" binding:"required,oneof=percent fixed free_days"`
+		Value     int64      `json:"value" binding:"required,min=1"`
+		MaxUses   int        `json:"maxUses" binding:"min=0"`
+		ExpiresAt *time.Time `json:"expiresAt"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	if request.Type == "percent" && request.Value > 100 {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	coupon := models.Coupon{
+		Code:      strings.ToUpper(request.Code),
+		Type:      request.Type,
+		Value:     request.Value,
+		MaxUses:   request.MaxUses,
+		ExpiresAt: request.ExpiresAt,
+		CreatedBy: userId,
+	}
+
+	var existingCount int64
+	if err := database.DB.Unscoped().Model(&models.Coupon{}).Where("code = ?", coupon.Code).Count(&existingCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	if existingCount > 0 {
+		c.JSON(http.StatusConflict, responses.ApiResponse{Status: http.StatusConflict, Message: messages.CouponCodeTaken, Data: nil})
+		return
+	}
+
+	if err := database.DB.Create(&coupon).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: coupon})
+}
+
+func GetCoupons(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	if !user.IsAdmin {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	var total int64
+	if err := database.DB.Model(&models.Coupon{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	var coupons []models.Coupon
+	if err := database.DB.Order("id DESC").Limit(limit).Offset(offset).Find(&coupons).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{
+		Status:  http.StatusOK,
+		Message: messages.GeneralSuccess,
+		Data: map[string]any{
+			"coupons": coupons,
+			"pagination": map[string]any{
+				"total": total,
+				"page":  page,
+				"limit": limit,
+				"pages": int((total + int64(limit) - 1) / int64(limit)),
+			},
+		},
+	})
+}
+
+func DeleteCoupon(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	if !user.IsAdmin {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	couponId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.CouponNotFound, Data: nil})
+		return
+	}
+
+	var coupon models.Coupon
+	if err := database.DB.First(&coupon, couponId).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.CouponNotFound, Data: nil})
+		return
+	}
+
+	if err := database.DB.Delete(&coupon).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: coupon})
+}
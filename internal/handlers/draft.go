@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/freakingeek/fenjoon/internal/auth"
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/messages"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/responses"
+	"github.com/freakingeek/fenjoon/internal/services"
+	"github.com/freakingeek/fenjoon/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func draftCharLimitMessage(entitlements services.Entitlements) string {
+	return fmt.Sprintf(messages.DraftCharLimitFormat, utils.ToPersianDigits(strconv.Itoa(entitlements.MaxStoryLength)))
+}
+
+func CreateDraft(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var request struct {
+		Text string `json:"text"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userId).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.UserNotFound, Data: nil})
+		return
+	}
+
+	entitlements := services.GetEntitlements(user)
+	if utf8.RuneCountInString(request.Text) > entitlements.MaxStoryLength {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: draftCharLimitMessage(entitlements), Data: nil})
+		return
+	}
+
+	var draftsCount int64
+	if err := database.DB.Model(&models.Story{}).Where("user_id = ? AND status = ?", userId, "draft").Count(&draftsCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	if !services.WithinQuota(entitlements.MaxDrafts, draftsCount) {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.DraftLimit, Data: nil})
+		return
+	}
+
+	draft := models.Story{Text: request.Text, UserID: userId, Status: "draft"}
+	if err := database.DB.Create(&draft).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.DraftSaved, Data: draft})
+}
+
+func GetDraftById(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	draftId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.DraftNotFound, Data: nil})
+		return
+	}
+
+	var draft models.Story
+	if err := database.DB.Where("id = ? AND user_id = ? AND status = ?", draftId, userId, "draft").First(&draft).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.DraftNotFound, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: draft})
+}
+
+func UpdateDraft(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	draftId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.DraftNotFound, Data: nil})
+		return
+	}
+
+	var request struct {
+		Text string `json:"text"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	var draft models.Story
+	if err := database.DB.Preload("User").Where("id = ? AND user_id = ? AND status = ?", draftId, userId, "draft").First(&draft).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.DraftNotFound, Data: nil})
+		return
+	}
+
+	entitlements := services.GetEntitlements(draft.User)
+	if utf8.RuneCountInString(request.Text) > entitlements.MaxStoryLength {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: draftCharLimitMessage(entitlements), Data: nil})
+		return
+	}
+
+	draft.Text = request.Text
+
+	if err := database.DB.Save(&draft).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.DraftSaved, Data: draft})
+}
+
+func DeleteDraft(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	draftId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.DraftNotFound, Data: nil})
+		return
+	}
+
+	var draft models.Story
+	if err := database.DB.Where("id = ? AND user_id = ? AND status = ?", draftId, userId, "draft").First(&draft).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.DraftNotFound, Data: nil})
+		return
+	}
+
+	if err := database.DB.Delete(&draft).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.DraftDeleted, Data: draft})
+}
+
+func PublishDraft(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	draftId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.DraftNotFound, Data: nil})
+		return
+	}
+
+	var draft models.Story
+	if err := database.DB.Preload("User").Where("id = ? AND user_id = ? AND status = ?", draftId, userId, "draft").First(&draft).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.DraftNotFound, Data: nil})
+		return
+	}
+
+	entitlements := services.GetEntitlements(draft.User)
+	if !isStoryLengthAllowed(draft.Text, entitlements) {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: storyCharLimitMessage(entitlements), Data: nil})
+		return
+	}
+
+	var story models.Story
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		published, err := services.PublishStory(tx, draft)
+		story = published
+		return err
+	})
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.StoryNotCreated, Data: nil})
+		return
+	}
+
+	services.NotifyMentionedUsers(story.User, story.Text, "%s توی داستانش بهت اشاره کرد", fmt.Sprintf("/story/%d", story.ID), "story", story.ID)
+	go services.EvaluateBadges(services.BadgeEventStoryCreated, userId)
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.StoryCreated, Data: story})
+}
+
+func GetCurrentUserDrafts(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	query := database.DB.Model(&models.Story{}).Where("user_id = ? AND status = ?", userId, "draft")
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	var drafts []models.Story
+	if err := query.Order("updated_at DESC").Limit(limit).Offset(offset).Find(&drafts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{
+		Status:  http.StatusOK,
+		Message: messages.GeneralSuccess,
+		Data: map[string]any{
+			"drafts": drafts,
+			"pagination": map[string]any{
+				"total": total,
+				"page":  page,
+				"limit": limit,
+				"pages": int((total + int64(limit) - 1) / int64(limit)),
+			},
+		},
+	})
+}
@@ -1,17 +1,36 @@
 package handlers
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/freakingeek/fenjoon/internal/auth"
 	"github.com/freakingeek/fenjoon/internal/database"
 	"github.com/freakingeek/fenjoon/internal/messages"
 	"github.com/freakingeek/fenjoon/internal/models"
 	"github.com/freakingeek/fenjoon/internal/responses"
+	"github.com/freakingeek/fenjoon/internal/services"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// Comments are sent this often so proxies and clients don't drop idle streams
+const notificationStreamHeartbeat = 25 * time.Second
+
+func writeNotificationEvent(w io.Writer, event services.NotificationEvent) {
+	if event.ID != "" {
+		fmt.Fprintf(w, "id: %s\n", event.ID)
+	}
+
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Event, event.Data)
+}
+
 func GetUserNotifications(c *gin.Context) {
 	userId, err := auth.GetUserIdFromContext(c)
 	if err != nil {
@@ -31,15 +50,35 @@ func GetUserNotifications(c *gin.Context) {
 
 	offset := (page - 1) * limit
 
+	query := database.DB.Model(&models.Notification{}).Where("user_id = ?", userId)
+
+	// Filter by read state, "true" or "false", and by one or more comma separated types
+	switch c.Query("read") {
+	case "true":
+		query = query.Where("is_read = ?", true)
+	case "false":
+		query = query.Where("is_read = ?", false)
+	}
+
+	if types := c.Query("type"); types != "" {
+		query = query.Where("type IN ?", strings.Split(types, ","))
+	}
+
 	var notifications []models.Notification
 	var total int64
 
-	if err := database.DB.Model(&models.Notification{}).Where("user_id = ?", userId).Count(&total).Error; err != nil {
+	if err := query.Count(&total).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
 		return
 	}
 
-	if err := database.DB.Order("id DESC").Where("user_id = ?", userId).Limit(limit).Offset(offset).Find(&notifications).Error; err != nil {
+	// Grouped notifications move up as new actors join them, reading them doesn't move them
+	if err := query.Preload("Actor").Order("COALESCE(last_actor_at, created_at) DESC, id DESC").Limit(limit).Offset(offset).Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	if err := services.LoadLatestActors(notifications); err != nil {
 		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
 		return
 	}
@@ -73,11 +112,19 @@ func GetNotificationById(c *gin.Context) {
 	}
 
 	var notification models.Notification
-	if err := database.DB.Where("id = ? AND user_id = ?", notificationId, userId).First(&notification).Error; err != nil {
+	if err := database.DB.Preload("Actor").Where("id = ? AND user_id = ?", notificationId, userId).First(&notification).Error; err != nil {
 		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.GeneralNotFound, Data: nil})
 		return
 	}
 
+	notifications := []models.Notification{notification}
+	if err := services.LoadLatestActors(notifications); err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	notification = notifications[0]
+
 	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: notification})
 }
 
@@ -98,7 +145,7 @@ func GetUserNotificationsUnreadCount(c *gin.Context) {
 }
 
 func MarkNotificationsAsRead(c *gin.Context) {
-	_, err := auth.GetUserIdFromContext(c)
+	userId, err := auth.GetUserIdFromContext(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
 		return
@@ -118,7 +165,7 @@ func MarkNotificationsAsRead(c *gin.Context) {
 		return
 	}
 
-	result := database.DB.Model(&models.Notification{}).Where("id IN (?)", request.IDs).Updates(map[string]any{"is_read": true})
+	result := database.DB.Model(&models.Notification{}).Where("id IN (?) AND user_id = ?", request.IDs, userId).Updates(map[string]any{"is_read": true})
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
 		return
@@ -129,5 +176,300 @@ func MarkNotificationsAsRead(c *gin.Context) {
 		return
 	}
 
+	go services.PublishUnreadCount(userId)
+
 	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: true})
 }
+
+func MarkAllNotificationsAsRead(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	result := database.DB.Model(&models.Notification{}).Where("user_id = ? AND is_read = ?", userId, false).Updates(map[string]any{"is_read": true})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	go services.PublishUnreadCount(userId)
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: result.RowsAffected})
+}
+
+func DeleteNotification(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	notificationId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralNotFound, Data: nil})
+		return
+	}
+
+	result := database.DB.Where("id = ? AND user_id = ?", notificationId, userId).Delete(&models.Notification{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.GeneralNotFound, Data: nil})
+		return
+	}
+
+	go services.PublishUnreadCount(userId)
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: true})
+}
+
+// DeleteAllNotifications clears the user's notifications, only the read ones with read=true.
+func DeleteAllNotifications(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	query := database.DB.Where("user_id = ?", userId)
+	if c.Query("read") == "true" {
+		query = query.Where("is_read = ?", true)
+	}
+
+	result := query.Delete(&models.Notification{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	go services.PublishUnreadCount(userId)
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: result.RowsAffected})
+}
+
+// StreamNotifications pushes new notifications and unread count changes over Server-Sent Events. Clients resume
+// with the Last-Event-ID header, or the lastEventId query parameter, and get the events they missed first.
+func StreamNotifications(c *gin.Context) {
+	userId, err := auth.GetUserIdFromStreamContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	lastEventId := c.GetHeader("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = c.Query("lastEventId")
+	}
+
+	if lastEventId != "" && !services.IsValidNotificationEventId(lastEventId) {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	var unreadCount int64
+	if err := database.DB.Model(&models.Notification{}).Where("user_id = ? AND is_read = false", userId).Count(&unreadCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	// Subscribe before replaying, events arriving in between are skipped by ID below instead of being lost
+	events, unsubscribe := services.SubscribeNotificationEvents(userId)
+	defer unsubscribe()
+
+	var missed []services.NotificationEvent
+	if lastEventId != "" {
+		missed, err = services.GetNotificationEventsSince(userId, lastEventId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+
+	for _, event := range missed {
+		writeNotificationEvent(c.Writer, event)
+		lastEventId = event.ID
+	}
+
+	count, _ := json.Marshal(map[string]any{"count": unreadCount})
+	writeNotificationEvent(c.Writer, services.NotificationEvent{Event: services.NotificationEventUnreadCount, Data: count})
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(notificationStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+		case event := <-events:
+			if !services.IsNotificationEventAfter(event.ID, lastEventId) {
+				continue
+			}
+
+			writeNotificationEvent(c.Writer, event)
+			lastEventId = event.ID
+			c.Writer.Flush()
+		}
+	}
+}
+
+func GetCurrentUserNotificationSettings(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	settings, err := services.GetNotificationSettings(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: settings})
+}
+
+func UpdateCurrentUserNotificationSettings(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var request struct {
+		Settings []struct {
+			Type  string `json:"type" binding:"required"`
+			InApp bool   `json:"inApp"`
+			Push  bool   `json:"push"`
+		} `json:"settings" binding:"required,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	for _, setting := range request.Settings {
+		if !services.IsConfigurableNotificationType(setting.Type) {
+			c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.NotificationTypeInvalid, Data: nil})
+			return
+		}
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, setting := range request.Settings {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+				DoUpdates: clause.AssignmentColumns([]string{"in_app", "push", "updated_at"}),
+			}).Create(&models.NotificationSetting{UserID: userId, Type: setting.Type, InApp: setting.InApp, Push: setting.Push}).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	settings, err := services.GetNotificationSettings(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.NotificationSettingsSaved, Data: settings})
+}
+
+func GetCurrentUserQuietHours(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	quietHours, err := services.GetQuietHours(userId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.UserNotFound, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: quietHours})
+}
+
+// UpdateCurrentUserQuietHours saves the user's quiet hours and, when given, their timezone.
+func UpdateCurrentUserQuietHours(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var request struct {
+		Enabled  bool   `json:"enabled"`
+		Start    string `json:"start" binding:"required"`
+		End      string `json:"end" binding:"required"`
+		Timezone string `json:"timezone"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	if !services.IsValidQuietHoursTime(request.Start) || !services.IsValidQuietHoursTime(request.End) {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.QuietHoursInvalid, Data: nil})
+		return
+	}
+
+	if request.Timezone != "" && !services.IsValidTimezone(request.Timezone) {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.TimezoneInvalid, Data: nil})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"enabled", "start_time", "end_time", "updated_at"}),
+		}).Create(&models.QuietHours{UserID: userId, Enabled: request.Enabled, Start: request.Start, End: request.End}).Error; err != nil {
+			return err
+		}
+
+		if request.Timezone == "" {
+			return nil
+		}
+
+		return tx.Model(&models.User{}).Where("id = ?", userId).Update("timezone", request.Timezone).Error
+	})
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	quietHours, err := services.GetQuietHours(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.QuietHoursSaved, Data: quietHours})
+}
@@ -3,16 +3,20 @@ package handlers
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/freakingeek/fenjoon/internal/auth"
 	"github.com/freakingeek/fenjoon/internal/database"
 	"github.com/freakingeek/fenjoon/internal/messages"
 	"github.com/freakingeek/fenjoon/internal/models"
 	"github.com/freakingeek/fenjoon/internal/responses"
+	"github.com/freakingeek/fenjoon/internal/services"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// RegisterPushToken saves a device's token, or refreshes it when the device registers again. Each of a user's devices keeps its own token.
 func RegisterPushToken(c *gin.Context) {
 	userId, err := auth.GetUserIdFromContext(c)
 	if err != nil {
@@ -20,7 +24,8 @@ func RegisterPushToken(c *gin.Context) {
 	}
 
 	var request struct {
-		Token string `json:"token" binding:"required"`
+		Token    string `json:"token" binding:"required"`
+		Platform string `json:"platform" binding:"omitempty,oneof=ios android web"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -28,36 +33,29 @@ func RegisterPushToken(c *gin.Context) {
 		return
 	}
 
-	var pushToken models.PushToken
-	err = database.DB.Where("token = ?", request.Token).First(&pushToken).Error
-
-	if err == nil {
-		if pushToken.UserID == 0 && userId != 0 {
-			pushToken.UserID = userId
-			if err := database.DB.Save(&pushToken).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
-				return
-			}
-		}
-	} else if errors.Is(err, gorm.ErrRecordNotFound) {
-		var existingToken models.PushToken
-		err = database.DB.Where("user_id = ?", userId).First(&existingToken).Error
-
-		if err == nil {
-			existingToken.Token = request.Token
-			if err := database.DB.Save(&existingToken).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
-				return
-			}
-		} else {
-			// NOTE: Push token doesn't exist; create a new one
-			newPushToken := models.PushToken{UserID: userId, Token: request.Token}
-			if err := database.DB.Create(&newPushToken).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
-				return
-			}
-		}
-	} else {
+	now := time.Now()
+
+	// A device registering again refreshes its row, also when it was unregistered or pruned. It may have logged in
+	// to another account since. Registrations racing each other, like an app start and a login, meet on the token.
+	updates := []string{"last_seen_at", "deleted_at"}
+	if userId != 0 {
+		updates = append(updates, "user_id")
+	}
+
+	if request.Platform != "" {
+		updates = append(updates, "platform")
+	}
+
+	pushToken := models.PushToken{UserID: userId, Token: request.Token, Platform: request.Platform, LastSeenAt: &now}
+	if err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "token"}},
+		DoUpdates: clause.AssignmentColumns(updates),
+	}).Create(&pushToken).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	if err := database.DB.Where("token = ?", request.Token).First(&pushToken).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
 		return
 	}
@@ -93,3 +91,98 @@ func UnregisterPushToken(c *gin.Context) {
 
 	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: pushToken})
 }
+
+func GetVapidPublicKey(c *gin.Context) {
+	publicKey, err := services.GetVapidPublicKey()
+	if err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.GeneralNotFound, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: publicKey})
+}
+
+// RegisterWebPushSubscription saves a browser's push subscription, or refreshes it when the browser subscribes again.
+func RegisterWebPushSubscription(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		userId = 0
+	}
+
+	var request struct {
+		Endpoint string `json:"endpoint" binding:"required,url"`
+		Keys     struct {
+			P256dh string `json:"p256dh" binding:"required,max=128"`
+			Auth   string `json:"auth" binding:"required,max=32"`
+		} `json:"keys" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	if !services.IsAllowedWebPushEndpoint(request.Endpoint) {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.WebPushEndpointInvalid, Data: nil})
+		return
+	}
+
+	now := time.Now()
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 256 {
+		userAgent = userAgent[:256]
+	}
+
+	var subscription models.WebPushSubscription
+	err = database.DB.Where("endpoint = ?", request.Endpoint).First(&subscription).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		subscription = models.WebPushSubscription{UserID: userId, Endpoint: request.Endpoint}
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	} else if userId != 0 {
+		subscription.UserID = userId
+	}
+
+	subscription.P256dh = request.Keys.P256dh
+	subscription.Auth = request.Keys.Auth
+	subscription.UserAgent = userAgent
+	subscription.LastSeenAt = &now
+
+	if err := database.DB.Save(&subscription).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: subscription})
+}
+
+func UnregisterWebPushSubscription(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		userId = 0
+	}
+
+	var request struct {
+		Endpoint string `json:"endpoint" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	var subscription models.WebPushSubscription
+	if err := database.DB.Where("endpoint = ? AND user_id = ?", request.Endpoint, userId).First(&subscription).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.GeneralNotFound, Data: nil})
+		return
+	}
+
+	if err := database.DB.Delete(&subscription).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: subscription})
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/auth"
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/messages"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/responses"
+	"github.com/freakingeek/fenjoon/internal/services"
+	"github.com/freakingeek/fenjoon/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const referralRewardDays = 7
+
+// Ambiguous characters (0/O, 1/I) are left out so codes can be read aloud and typed by hand
+const referralCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func generateReferralCode() (string, error) {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	for i := range bytes {
+		bytes[i] = referralCodeAlphabet[int(bytes[i])%len(referralCodeAlphabet)]
+	}
+
+	return string(bytes), nil
+}
+
+// rewardReferrer credits whoever invited a newly registered user, either through a phone invite or a shared referral code.
+func rewardReferrer(user models.User, referralCode string) {
+	var referral models.Referral
+	if err := database.DB.Where("phone = ? AND referred_user_id = 0", user.Phone).Order("id ASC").First(&referral).Error; err != nil {
+		if referralCode == "" {
+			return
+		}
+
+		var referrer models.User
+		if err := database.DB.Where("referral_code = ?", strings.ToUpper(strings.TrimSpace(referralCode))).First(&referrer).Error; err != nil {
+			return
+		}
+
+		referral = models.Referral{ReferrerID: referrer.ID, Phone: user.Phone}
+	}
+
+	if referral.ReferrerID == user.ID {
+		return
+	}
+
+	now := time.Now()
+	referral.ReferredUserID = user.ID
+	referral.RewardDays = referralRewardDays
+	referral.RewardedAt = &now
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&referral).Error; err != nil {
+			return err
+		}
+
+		_, err := services.GrantPremium(tx, referral.ReferrerID, "referral", referralRewardDays*24*time.Hour, 0)
+		return err
+	})
+
+	if err != nil {
+		fmt.Printf("Failed to reward referrer %d: %v\n", referral.ReferrerID, err)
+		return
+	}
+
+	services.NotifyUser(models.Notification{
+		UserID:     referral.ReferrerID,
+		Type:       services.NotificationReferralRewarded,
+		ActorID:    &user.ID,
+		TargetType: "user",
+		TargetID:   &user.ID,
+		Title:      "دعوتت نتیجه داد!",
+		Message:    fmt.Sprintf("%s با دعوت تو به فنجون پیوست و %s روز اشتراک حرفه‌ای هدیه گرفتی", utils.GetUserDisplayName(user), utils.ToPersianDigits(fmt.Sprint(referralRewardDays))),
+		Url:        "/premium",
+	})
+}
+
+func GetCurrentUserReferral(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.UserNotFound, Data: nil})
+		return
+	}
+
+	if user.ReferralCode == "" {
+		code, err := generateReferralCode()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+			return
+		}
+
+		if err := database.DB.Model(&user).Update("referral_code", code).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+			return
+		}
+
+		user.ReferralCode = code
+	}
+
+	var invitedCount int64
+	if err := database.DB.Model(&models.Referral{}).Where("referrer_id = ?", userId).Count(&invitedCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	var joinedCount int64
+	if err := database.DB.Model(&models.Referral{}).Where("referrer_id = ? AND referred_user_id <> 0", userId).Count(&joinedCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: map[string]any{
+		"code":         user.ReferralCode,
+		"url":          fmt.Sprintf("%s/join?ref=%s", getAppBaseUrl(), user.ReferralCode),
+		"rewardDays":   referralRewardDays,
+		"invitedCount": invitedCount,
+		"joinedCount":  joinedCount,
+		"rewardedDays": joinedCount * referralRewardDays,
+	}})
+}
+
+func InviteByPhone(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var request struct {
+		Phone string `json:"phone" binding:"required,numeric,len=11,startswith=09"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	var registeredCount int64
+	if err := database.DB.Model(&models.User{}).Where("phone = ?", request.Phone).Count(&registeredCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	if registeredCount > 0 {
+		c.JSON(http.StatusConflict, responses.ApiResponse{Status: http.StatusConflict, Message: messages.ReferralPhoneRegistered, Data: nil})
+		return
+	}
+
+	var invitedCount int64
+	if err := database.DB.Model(&models.Referral{}).Where("phone = ?", request.Phone).Count(&invitedCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	if invitedCount > 0 {
+		c.JSON(http.StatusConflict, responses.ApiResponse{Status: http.StatusConflict, Message: messages.ReferralAlreadyInvited, Data: nil})
+		return
+	}
+
+	referral := models.Referral{ReferrerID: userId, Phone: request.Phone}
+	if err := database.DB.Create(&referral).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.ReferralInvited, Data: referral})
+}
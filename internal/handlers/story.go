@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
+	"unicode/utf8"
 
 	"github.com/freakingeek/fenjoon/internal/auth"
 	"github.com/freakingeek/fenjoon/internal/database"
@@ -17,6 +20,34 @@ import (
 	"gorm.io/gorm"
 )
 
+const minStoryLength = 25
+
+// storyScheduleWindow is how far ahead a story can be scheduled.
+const storyScheduleWindow = 30 * 24 * time.Hour
+
+// isStoryLengthAllowed checks the story text against the minimum length and the author's plan limit.
+func isStoryLengthAllowed(text string, entitlements services.Entitlements) bool {
+	length := utf8.RuneCountInString(text)
+	return length >= minStoryLength && length <= entitlements.MaxStoryLength
+}
+
+func storyCharLimitMessage(entitlements services.Entitlements) string {
+	return fmt.Sprintf(messages.StoryCharLimitFormat, utils.ToPersianDigits(strconv.Itoa(entitlements.MaxStoryLength)))
+}
+
+func isValidPublishAt(publishAt time.Time) bool {
+	now := time.Now()
+	return publishAt.After(now) && publishAt.Before(now.Add(storyScheduleWindow))
+}
+
+// publishedStories hides drafts and other unpublished stories from a query.
+func publishedStories(db *gorm.DB) *gorm.DB {
+	return db.Where("stories.status = ?", "published")
+}
+
+// Newest published first. Stories published before published_at existed fall back to when they were created.
+const storiesByPublishedAt = "COALESCE(stories.published_at, stories.created_at) DESC, stories.id DESC"
+
 func CreateStory(c *gin.Context) {
 	userId, err := auth.GetUserIdFromContext(c)
 	if err != nil {
@@ -25,7 +56,9 @@ func CreateStory(c *gin.Context) {
 	}
 
 	var request struct {
-		Text string `json:"text" binding:"required,min=25,max=250"`
+		Text      string     `json:"text" binding:"required"`
+		PublishAt *time.Time `json:"publishAt"`
+		ParentID  *uint      `json:"parentId"` // Story being continued
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -33,13 +66,53 @@ func CreateStory(c *gin.Context) {
 		return
 	}
 
-	story := models.Story{Text: request.Text, UserID: userId}
+	if request.PublishAt != nil && !isValidPublishAt(*request.PublishAt) {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.StoryScheduleInvalidTime, Data: nil})
+		return
+	}
+
+	if request.ParentID != nil {
+		if status, message := findContinuableStory(*request.ParentID, userId); status != http.StatusOK {
+			c.JSON(status, responses.ApiResponse{Status: status, Message: message, Data: nil})
+			return
+		}
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userId).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.UserNotFound, Data: nil})
+		return
+	}
+
+	entitlements := services.GetEntitlements(user)
+	if !isStoryLengthAllowed(request.Text, entitlements) {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: storyCharLimitMessage(entitlements), Data: nil})
+		return
+	}
+
+	now := time.Now()
+	story := models.Story{Text: request.Text, UserID: userId, ParentID: request.ParentID, Status: "published", PublishedAt: &now}
+	if request.PublishAt != nil {
+		story.Status = "scheduled"
+		story.PublishAt = request.PublishAt
+		story.PublishedAt = nil
+	}
 
 	if err := database.DB.Create(&story).Preload("User").First(&story, story.ID).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.StoryNotCreated, Data: nil})
 		return
 	}
 
+	// Mentions and followers are notified by the scheduler once the story goes out
+	if story.Status == "scheduled" {
+		c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.StoryScheduled, Data: story})
+		return
+	}
+
+	services.NotifyMentionedUsers(story.User, story.Text, "%s توی داستانش بهت اشاره کرد", fmt.Sprintf("/story/%d", story.ID), "story", story.ID)
+	services.NotifyContinuedAuthor(story)
+	go services.EvaluateBadges(services.BadgeEventStoryCreated, userId)
+
 	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.StoryCreated, Data: story})
 }
 
@@ -62,14 +135,15 @@ func GetAllStories(c *gin.Context) {
 
 	offset := (page - 1) * limit
 
-	if err := database.DB.Model(&models.Story{}).Where("is_private = ?", false).Count(&total).Error; err != nil {
+	if err := database.DB.Model(&models.Story{}).Scopes(publishedStories).Where("is_private = ?", false).Count(&total).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
 		return
 	}
 
 	if err := database.DB.Preload("User").
+		Scopes(publishedStories).
 		Where("is_private = ?", false).
-		Order("id DESC").
+		Order(storiesByPublishedAt).
 		Limit(limit).
 		Offset(offset).
 		Find(&stories).Error; err != nil {
@@ -77,6 +151,15 @@ func GetAllStories(c *gin.Context) {
 		return
 	}
 
+	impressions := []uint{}
+	for _, story := range stories {
+		if story.UserID != userId {
+			impressions = append(impressions, story.ID)
+		}
+	}
+
+	services.RecordStoryStats("impressions", impressions, getViewerKey(c, userId))
+
 	for i := range stories {
 		var likesCount int64
 		if err := database.DB.Model(&models.Like{}).Where("story_id = ?", stories[i].ID).Count(&likesCount).Error; err != nil {
@@ -139,7 +222,7 @@ func GetStoryById(c *gin.Context) {
 	}
 
 	var story models.Story
-	if err := database.DB.Preload("User").Where("id = ?", storyId).First(&story).Error; err != nil {
+	if err := database.DB.Preload("User").Scopes(publishedStories).Where("id = ?", storyId).First(&story).Error; err != nil {
 		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
 		return
 	}
@@ -149,6 +232,16 @@ func GetStoryById(c *gin.Context) {
 		return
 	}
 
+	if story.UserID != userId {
+		services.RecordStoryStats("views", []uint{story.ID}, getViewerKey(c, userId))
+	}
+
+	var viewsCount int64
+	if err := database.DB.Model(&models.StoryDailyStat{}).Where("story_id = ?", storyId).Select("COALESCE(SUM(views), 0)").Scan(&viewsCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
 	var likesCount int64
 	if err := database.DB.Model(&models.Like{}).Where("story_id = ?", storyId).Count(&likesCount).Error; err != nil {
 		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
@@ -176,13 +269,37 @@ func GetStoryById(c *gin.Context) {
 		return
 	}
 
+	series, err := getStorySeries(story, userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	ancestry, err := getStoryAncestry(story, userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	var continuationsCount int64
+	if err := database.DB.Model(&models.Story{}).Scopes(publishedStories).
+		Where("parent_id = ? AND (is_private = ? OR user_id = ?)", story.ID, false, userId).
+		Count(&continuationsCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
 	story.LikesCount = uint(likesCount)
 	story.SharesCount = uint(sharesCount)
 	story.CommentsCount = uint(commentsCount)
+	story.ViewsCount = uint(viewsCount)
 	story.IsLikedByUser = isLikedByUser
 	story.IsEditableByUser = userId == story.UserID
 	story.IsDeletableByUser = userId == story.UserID
 	story.IsPrivatableByUser = userId == story.UserID
+	story.Series = series
+	story.Ancestry = ancestry
+	story.ContinuationsCount = uint(continuationsCount)
 
 	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: story})
 }
@@ -195,7 +312,8 @@ func UpdateStory(c *gin.Context) {
 	}
 
 	var request struct {
-		Text string `json:"text" binding:"required,min=25,max=250"`
+		Text      string     `json:"text" binding:"required"`
+		PublishAt *time.Time `json:"publishAt"` // Only applies to scheduled stories
 	}
 
 	storyId, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -211,14 +329,42 @@ func UpdateStory(c *gin.Context) {
 
 	var story models.Story
 
-	if err := database.DB.Preload("User").Where("id = ? AND user_id = ?", storyId, userId).First(&story).Error; err != nil {
+	if err := database.DB.Preload("User").Where("id = ? AND user_id = ? AND status IN ?", storyId, userId, []string{"published", "scheduled"}).First(&story).Error; err != nil {
 		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
 		return
 	}
 
-	story.Text = request.Text
+	entitlements := services.GetEntitlements(story.User)
+	if !isStoryLengthAllowed(request.Text, entitlements) {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: storyCharLimitMessage(entitlements), Data: nil})
+		return
+	}
 
-	if err := database.DB.Save(&story).Error; err != nil {
+	if request.PublishAt != nil && story.Status == "scheduled" {
+		if !isValidPublishAt(*request.PublishAt) {
+			c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.StoryScheduleInvalidTime, Data: nil})
+			return
+		}
+
+		story.PublishAt = request.PublishAt
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Revisions start once the story is out, a scheduled story is still edited freely
+		if story.Status == "published" && request.Text != story.Text {
+			if err := recordStoryRevision(tx, story, request.Text); err != nil {
+				return err
+			}
+
+			story.IsEdited = true
+		}
+
+		story.Text = request.Text
+
+		return tx.Save(&story).Error
+	})
+
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
 		return
 	}
@@ -268,7 +414,7 @@ func LikeStoryById(c *gin.Context) {
 	}
 
 	var story models.Story
-	if err := database.DB.First(&story, storyId).Error; err != nil {
+	if err := database.DB.Scopes(publishedStories).First(&story, storyId).Error; err != nil {
 		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
 		return
 	}
@@ -286,25 +432,34 @@ func LikeStoryById(c *gin.Context) {
 	}
 
 	like := models.Like{StoryID: uint(storyId), UserID: userId}
-	if err := database.DB.Create(&like).Error; err != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&like).Error; err != nil {
+			return err
+		}
+
+		if userId == story.UserID {
+			return nil
+		}
+
+		return services.EnqueueNotification(tx, models.Notification{
+			UserID:     story.UserID,
+			Type:       services.NotificationStoryLiked,
+			ActorID:    &userId,
+			TargetType: "story",
+			TargetID:   &story.ID,
+			Title:      "داستانت پسندیده شد!",
+			Message:    fmt.Sprintf("%s از داستانت خوشش اومد", utils.GetUserDisplayName(user)),
+			Url:        fmt.Sprintf("/author/%d", story.UserID),
+		})
+	})
+
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
 		return
 	}
 
 	if userId != story.UserID {
-		text := fmt.Sprintf("%s از داستانت خوشش اومد", utils.GetUserDisplayName(user))
-
-		notification := models.Notification{UserID: story.UserID, Title: "داستانت پسندیده شد!", Message: text, Url: fmt.Sprintf("/author/%d", story.UserID)}
-		if err := services.SendInAppNotification(notification); err != nil {
-			fmt.Printf("Failed to send in-app notification: %v\n", err)
-		}
-
-		var pushToken models.PushToken
-		if err := database.DB.Where("user_id = ?", story.UserID).First(&pushToken).Error; err == nil {
-			if err := services.SendPushNotification([]string{pushToken.Token}, text); err != nil {
-				fmt.Printf("Failed to send push notification: %v\n", err)
-			}
-		}
+		go services.EvaluateBadges(services.BadgeEventLikeReceived, story.UserID)
 	}
 
 	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.StoryLiked, Data: true})
@@ -324,7 +479,7 @@ func DislikeStoryById(c *gin.Context) {
 	}
 
 	var story models.Story
-	if err := database.DB.First(&story, storyId).Error; err != nil {
+	if err := database.DB.Scopes(publishedStories).First(&story, storyId).Error; err != nil {
 		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
 		return
 	}
@@ -351,7 +506,7 @@ func GetStoryLikers(c *gin.Context) {
 	}
 
 	var story models.Story
-	if err := database.DB.First(&story, storyId).Error; err != nil {
+	if err := database.DB.Scopes(publishedStories).First(&story, storyId).Error; err != nil {
 		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
 		return
 	}
@@ -454,23 +609,8 @@ func CommentStoryById(c *gin.Context) {
 		return
 	}
 
-	comment := models.Comment{StoryID: uint(storyId), UserID: uint(userId), Text: request.Text}
-	if err := database.DB.Create(&comment).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
-		return
-	}
-
-	if err := database.DB.Preload("User").First(&comment, comment.ID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, responses.ApiResponse{
-			Status:  http.StatusInternalServerError,
-			Message: messages.GeneralFailed,
-			Data:    nil,
-		})
-		return
-	}
-
 	var story models.Story
-	if err := database.DB.First(&story, storyId).Error; err != nil {
+	if err := database.DB.Scopes(publishedStories).First(&story, storyId).Error; err != nil {
 		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
 		return
 	}
@@ -481,20 +621,44 @@ func CommentStoryById(c *gin.Context) {
 		return
 	}
 
-	if userId != story.UserID {
-		text := fmt.Sprintf("%s نقد جدیدی روی داستانت ثبت کرد", utils.GetUserDisplayName(user))
-
-		notification := models.Notification{UserID: story.UserID, Title: "داستانت نقد جدیدی گرفت!", Message: text, Url: fmt.Sprintf("/story/%d", story.ID)}
-		if err := services.SendInAppNotification(notification); err != nil {
-			fmt.Printf("Failed to send in-app notification: %v\n", err)
+	comment := models.Comment{StoryID: uint(storyId), UserID: uint(userId), Text: request.Text}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
 		}
 
-		var pushToken models.PushToken
-		if err := database.DB.Where("user_id = ?", story.UserID).First(&pushToken).Error; err == nil {
-			if err := services.SendPushNotification([]string{pushToken.Token}, text); err != nil {
-				fmt.Printf("Failed to send push notification: %v\n", err)
-			}
+		if userId == story.UserID {
+			return nil
 		}
+
+		return services.EnqueueNotification(tx, models.Notification{
+			UserID:     story.UserID,
+			Type:       services.NotificationStoryCommented,
+			ActorID:    &userId,
+			TargetType: "story",
+			TargetID:   &story.ID,
+			Title:      "داستانت نقد جدیدی گرفت!",
+			Message:    fmt.Sprintf("%s نقد جدیدی روی داستانت ثبت کرد", utils.GetUserDisplayName(user)),
+			Url:        fmt.Sprintf("/story/%d", story.ID),
+		})
+	})
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	if err := database.DB.Preload("User").First(&comment, comment.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{
+			Status:  http.StatusInternalServerError,
+			Message: messages.GeneralFailed,
+			Data:    nil,
+		})
+		return
+	}
+
+	if !story.IsPrivate {
+		services.NotifyMentionedUsers(user, comment.Text, "%s توی نقدش بهت اشاره کرد", fmt.Sprintf("/story/%d", story.ID), "comment", comment.ID)
 	}
 
 	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: comment})
@@ -650,7 +814,7 @@ func GetAuthorOtherStories(c *gin.Context) {
 	}
 
 	var story models.Story
-	if err := database.DB.First(&story, storyId).Error; err != nil {
+	if err := database.DB.Scopes(publishedStories).First(&story, storyId).Error; err != nil {
 		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
 		return
 	}
@@ -661,9 +825,9 @@ func GetAuthorOtherStories(c *gin.Context) {
 	}
 
 	var relatedStories []models.Story
-	query := database.DB.Where("user_id = ? AND id != ? AND is_private = ?", story.UserID, story.ID, false)
+	query := database.DB.Scopes(publishedStories).Where("user_id = ? AND id != ? AND is_private = ?", story.UserID, story.ID, false)
 
-	if err := query.Preload("User").Order("id DESC").Limit(5).Find(&relatedStories).Error; err != nil {
+	if err := query.Preload("User").Order(storiesByPublishedAt).Limit(5).Find(&relatedStories).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
 		return
 	}
@@ -730,25 +894,19 @@ func ChangeStoryVisibility(c *gin.Context) {
 	}
 
 	var story models.Story
-	if err := database.DB.Preload("User").Where("id = ? AND user_id = ?", storyId, userId).First(&story).Error; err != nil {
+	if err := database.DB.Preload("User").Scopes(publishedStories).Where("id = ? AND user_id = ?", storyId, userId).First(&story).Error; err != nil {
 		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
 		return
 	}
 
 	if request.IsPrivate && !story.IsPrivate {
 		var privateStoriesCount int64
-		if err := database.DB.Model(&models.Story{}).Where("user_id = ? AND is_private = ?", userId, true).Count(&privateStoriesCount).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
-			return
-		}
-
-		var user models.User
-		if err := database.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+		if err := database.DB.Model(&models.Story{}).Scopes(publishedStories).Where("user_id = ? AND is_private = ?", userId, true).Count(&privateStoriesCount).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
 			return
 		}
 
-		if !user.IsPremium && privateStoriesCount >= 3 {
+		if !services.WithinQuota(services.GetEntitlements(story.User).MaxPrivateStories, privateStoriesCount) {
 			c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralNeedsPremium, Data: nil})
 			return
 		}
@@ -778,7 +936,7 @@ func BookmarkStory(c *gin.Context) {
 	}
 
 	var story models.Story
-	if err := database.DB.First(&story, storyId).Error; err != nil {
+	if err := database.DB.Scopes(publishedStories).First(&story, storyId).Error; err != nil {
 		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
 		return
 	}
@@ -789,13 +947,29 @@ func BookmarkStory(c *gin.Context) {
 		return
 	}
 
+	// The body is optional, a plain bookmark goes to no folder and has no note
+	var request struct {
+		FolderID *uint  `json:"folderId"`
+		Note     string `json:"note" binding:"max=512"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
 	var existingBookmark models.Bookmark
 	if err := database.DB.Where("story_id = ? AND user_id = ?", storyId, userId).First(&existingBookmark).Error; err == nil {
 		c.JSON(http.StatusConflict, responses.ApiResponse{Status: http.StatusConflict, Message: messages.StoryAlreadyBookmarked, Data: nil})
 		return
 	}
 
-	bookmark := models.Bookmark{StoryID: uint(storyId), UserID: userId}
+	if err := checkBookmarkFolder(request.FolderID, userId); err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.BookmarkFolderNotFound, Data: nil})
+		return
+	}
+
+	bookmark := models.Bookmark{StoryID: uint(storyId), UserID: userId, FolderID: request.FolderID, Note: request.Note}
 	if err := database.DB.Create(&bookmark).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
 		return
@@ -804,6 +978,52 @@ func BookmarkStory(c *gin.Context) {
 	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: true})
 }
 
+// UpdateBookmark moves a bookmark to another folder and replaces its note.
+func UpdateBookmark(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	storyId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	var request struct {
+		FolderID *uint  `json:"folderId"`
+		Note     string `json:"note" binding:"max=512"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	var bookmark models.Bookmark
+	if err := database.DB.Where("story_id = ? AND user_id = ?", storyId, userId).First(&bookmark).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.BookmarkNotFound, Data: nil})
+		return
+	}
+
+	if err := checkBookmarkFolder(request.FolderID, userId); err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.BookmarkFolderNotFound, Data: nil})
+		return
+	}
+
+	bookmark.FolderID = request.FolderID
+	bookmark.Note = request.Note
+
+	if err := database.DB.Model(&bookmark).Select("folder_id", "note").Updates(&bookmark).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.BookmarkEdited, Data: bookmark})
+}
+
 func UnBookmarkStory(c *gin.Context) {
 	userId, err := auth.GetUserIdFromContext(c)
 	if err != nil {
@@ -818,7 +1038,7 @@ func UnBookmarkStory(c *gin.Context) {
 	}
 
 	var story models.Story
-	if err := database.DB.First(&story, storyId).Error; err != nil {
+	if err := database.DB.Scopes(publishedStories).First(&story, storyId).Error; err != nil {
 		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
 		return
 	}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/auth"
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/messages"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/responses"
+	"github.com/freakingeek/fenjoon/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type storyAnalyticsDay struct {
+	Date            string `json:"date"`
+	Views           int64  `json:"views"`
+	Impressions     int64  `json:"impressions"`
+	Likes           int64  `json:"likes"`
+	Comments        int64  `json:"comments"`
+	Shares          int64  `json:"shares"`
+	FollowersGained int64  `json:"followersGained"`
+}
+
+// getViewerKey identifies a viewer for deduplicating views, by account or by IP for guests.
+func getViewerKey(c *gin.Context, userId uint) string {
+	if userId != 0 {
+		return fmt.Sprintf("u:%d", userId)
+	}
+
+	return "ip:" + c.ClientIP()
+}
+
+// countStoryActivityByDay counts rows of model for the story since the given time, grouped by day.
+func countStoryActivityByDay(model any, storyColumn string, storyId uint, since time.Time) (map[string]int64, error) {
+	var rows []struct {
+		Day   time.Time
+		Count int64
+	}
+
+	if err := database.DB.Model(model).
+		Select("DATE(created_at) AS day, COUNT(*) AS count").
+		Where(storyColumn+" = ? AND created_at >= ?", storyId, since).
+		Group("day").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Day.Format(services.StoryStatsDayLayout)] = row.Count
+	}
+
+	return counts, nil
+}
+
+func getRate(count int64, views int64) float64 {
+	if views == 0 {
+		return 0
+	}
+
+	return float64(count) / float64(views)
+}
+
+func GetStoryAnalytics(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	storyId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.StoryNotFound, Data: nil})
+		return
+	}
+
+	var story models.Story
+	if err := database.DB.Preload("User").Scopes(publishedStories).Where("id = ? AND user_id = ?", storyId, userId).First(&story).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
+		return
+	}
+
+	entitlements := services.GetEntitlements(story.User)
+	if !entitlements.CanViewAnalytics {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralNeedsPremium, Data: nil})
+		return
+	}
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if days < 1 || days > entitlements.AnalyticsHistoryDays {
+		days = entitlements.AnalyticsHistoryDays
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	since := today.AddDate(0, 0, -(days - 1))
+
+	var stats []models.StoryDailyStat
+	if err := database.DB.Where("story_id = ? AND date >= ?", story.ID, since).Find(&stats).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	likes, err := countStoryActivityByDay(&models.Like{}, "story_id", story.ID, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	comments, err := countStoryActivityByDay(&models.Comment{}, "story_id", story.ID, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	shares, err := countStoryActivityByDay(&models.Share{}, "story_id", story.ID, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	followers, err := countStoryActivityByDay(&models.Follow{}, "story_id", story.ID, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	timeline := make([]storyAnalyticsDay, 0, days)
+	positions := make(map[string]int, days)
+	for day := since; !day.After(today); day = day.AddDate(0, 0, 1) {
+		date := day.Format(services.StoryStatsDayLayout)
+		positions[date] = len(timeline)
+		timeline = append(timeline, storyAnalyticsDay{
+			Date:            date,
+			Likes:           likes[date],
+			Comments:        comments[date],
+			Shares:          shares[date],
+			FollowersGained: followers[date],
+		})
+	}
+
+	for _, stat := range stats {
+		if i, ok := positions[stat.Date.Format(services.StoryStatsDayLayout)]; ok {
+			timeline[i].Views = stat.Views
+			timeline[i].Impressions = stat.Impressions
+		}
+	}
+
+	var totals storyAnalyticsDay
+	for _, day := range timeline {
+		totals.Views += day.Views
+		totals.Impressions += day.Impressions
+		totals.Likes += day.Likes
+		totals.Comments += day.Comments
+		totals.Shares += day.Shares
+		totals.FollowersGained += day.FollowersGained
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: map[string]any{
+		"days":           days,
+		"maxHistoryDays": entitlements.AnalyticsHistoryDays,
+		"totals": map[string]any{
+			"views":           totals.Views,
+			"impressions":     totals.Impressions,
+			"likes":           totals.Likes,
+			"comments":        totals.Comments,
+			"shares":          totals.Shares,
+			"followersGained": totals.FollowersGained,
+		},
+		"rates": map[string]any{
+			"viewRate":    getRate(totals.Views, totals.Impressions), // Feed impressions that were opened
+			"likeRate":    getRate(totals.Likes, totals.Views),
+			"commentRate": getRate(totals.Comments, totals.Views),
+		},
+		"timeline": timeline,
+	}})
+}
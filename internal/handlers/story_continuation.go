@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/freakingeek/fenjoon/internal/auth"
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/messages"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/responses"
+	"github.com/gin-gonic/gin"
+)
+
+// maxStoryAncestry bounds how far up the chain GetStoryById walks.
+const maxStoryAncestry = 50
+
+// findContinuableStory checks that the user can see the parent story and that its author allows continuations.
+func findContinuableStory(parentId uint, userId uint) (int, string) {
+	var parent models.Story
+	if err := database.DB.Scopes(publishedStories).First(&parent, parentId).Error; err != nil {
+		return http.StatusNotFound, messages.StoryNotFound
+	}
+
+	if parent.IsPrivate && parent.UserID != userId {
+		return http.StatusNotFound, messages.StoryNotFound
+	}
+
+	if parent.ContinuationsDisabled {
+		return http.StatusForbidden, messages.StoryContinuationsOff
+	}
+
+	return http.StatusOK, messages.GeneralSuccess
+}
+
+// getStoryAncestry walks up the parents of a story, stopping at the first one the user can't see.
+func getStoryAncestry(story models.Story, userId uint) ([]models.Story, error) {
+	ancestry := []models.Story{}
+
+	parentId := story.ParentID
+	for parentId != nil && len(ancestry) < maxStoryAncestry {
+		var parent models.Story
+		if err := database.DB.Preload("User").Scopes(publishedStories).Where("id = ?", *parentId).Limit(1).Find(&parent).Error; err != nil {
+			return nil, err
+		}
+
+		if parent.ID == 0 || (parent.IsPrivate && parent.UserID != userId) {
+			break
+		}
+
+		ancestry = append([]models.Story{parent}, ancestry...)
+		parentId = parent.ParentID
+	}
+
+	return ancestry, nil
+}
+
+func GetStoryContinuations(c *gin.Context) {
+	userId, _ := auth.GetUserIdFromContext(c)
+
+	storyId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.StoryNotFound, Data: nil})
+		return
+	}
+
+	var story models.Story
+	if err := database.DB.Scopes(publishedStories).First(&story, storyId).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
+		return
+	}
+
+	if story.IsPrivate && story.UserID != userId {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	query := database.DB.Model(&models.Story{}).Scopes(publishedStories).
+		Where("parent_id = ? AND (is_private = ? OR user_id = ?)", story.ID, false, userId)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	var continuations []models.Story
+	if err := query.Preload("User").Order("COALESCE(stories.published_at, stories.created_at) ASC, stories.id ASC").Limit(limit).Offset(offset).Find(&continuations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	// Each branch reports how many continuations it has, so clients can expand the tree one level at a time
+	for i := range continuations {
+		var continuationsCount int64
+		if err := database.DB.Model(&models.Story{}).Scopes(publishedStories).
+			Where("parent_id = ? AND (is_private = ? OR user_id = ?)", continuations[i].ID, false, userId).
+			Count(&continuationsCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+			return
+		}
+
+		continuations[i].ContinuationsCount = uint(continuationsCount)
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{
+		Status:  http.StatusOK,
+		Message: messages.GeneralSuccess,
+		Data: map[string]any{
+			"continuations": continuations,
+			"pagination": map[string]any{
+				"total": total,
+				"page":  page,
+				"limit": limit,
+				"pages": int((total + int64(limit) - 1) / int64(limit)),
+			},
+		},
+	})
+}
+
+func ChangeStoryContinuations(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var request struct {
+		ContinuationsDisabled bool `json:"continuationsDisabled"`
+	}
+
+	storyId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	var story models.Story
+	if err := database.DB.Preload("User").Where("id = ? AND user_id = ?", storyId, userId).First(&story).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
+		return
+	}
+
+	story.ContinuationsDisabled = request.ContinuationsDisabled
+
+	if err := database.DB.Save(&story).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: story})
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/auth"
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/messages"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/responses"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// recordStoryRevision stores the new text as a revision. The first edit also stores the original text,
+// dated when the story was published, so the history is complete.
+func recordStoryRevision(tx *gorm.DB, story models.Story, text string) error {
+	if !story.IsEdited {
+		publishedAt := story.CreatedAt
+		if story.PublishedAt != nil {
+			publishedAt = *story.PublishedAt
+		}
+
+		original := models.StoryRevision{StoryID: story.ID, Text: story.Text, CreatedAt: publishedAt}
+		if err := tx.Create(&original).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Create(&models.StoryRevision{StoryID: story.ID, Text: text}).Error
+}
+
+// getStoryTextAt returns the text the story had at the given time.
+func getStoryTextAt(storyId uint, at time.Time) (string, error) {
+	var revision models.StoryRevision
+	err := database.DB.Where("story_id = ? AND created_at <= ?", storyId, at).Order("created_at DESC, id DESC").First(&revision).Error
+	if err == nil {
+		return revision.Text, nil
+	}
+
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+
+	// Never edited, so the current text is the only one it ever had
+	var story models.Story
+	if err := database.DB.Unscoped().First(&story, storyId).Error; err != nil {
+		return "", err
+	}
+
+	return story.Text, nil
+}
+
+func GetStoryRevisions(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	storyId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.StoryNotFound, Data: nil})
+		return
+	}
+
+	var story models.Story
+	if err := database.DB.Scopes(publishedStories).First(&story, storyId).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
+		return
+	}
+
+	if story.UserID != userId {
+		var user models.User
+		if err := database.DB.Where("id = ?", userId).First(&user).Error; err != nil || !user.IsAdmin {
+			c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+			return
+		}
+	}
+
+	var revisions []models.StoryRevision
+	if err := database.DB.Where("story_id = ?", story.ID).Order("created_at DESC, id DESC").Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: map[string]any{
+		"story":     story,
+		"revisions": revisions,
+	}})
+}
@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/auth"
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/messages"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/responses"
+	"github.com/freakingeek/fenjoon/internal/services"
+	"github.com/freakingeek/fenjoon/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func getAppBaseUrl() string {
+	if appBaseUrl := os.Getenv("APP_BASE_URL"); appBaseUrl != "" {
+		return appBaseUrl
+	}
+
+	return "https://app.fenjoon.io"
+}
+
+// createPayment stores a new payment, reserving a use of its coupon with it.
+func createPayment(tx *gorm.DB, payment *models.Payment) error {
+	if err := tx.Create(payment).Error; err != nil {
+		return err
+	}
+
+	if payment.CouponID == 0 {
+		return nil
+	}
+
+	return redeemCoupon(tx, payment.CouponID, payment.UserID, payment.ID)
+}
+
+// fulfillPayment grants the purchased plan to the buyer, or to the recipient of a gift, once the payment is settled.
+func fulfillPayment(tx *gorm.DB, payment models.Payment, plan services.SubscriptionPlan) error {
+	recipientId := payment.UserID
+	if payment.RecipientID != 0 {
+		recipientId = payment.RecipientID
+	}
+
+	_, err := services.GrantPremium(tx, recipientId, plan.ID, plan.Duration, payment.ID)
+	return err
+}
+
+func failPayment(payment models.Payment) {
+	if err := services.FailPayment(payment); err != nil {
+		fmt.Printf("Failed to mark payment %d as failed: %v\n", payment.ID, err)
+	}
+}
+
+func notifyGiftRecipient(payment models.Payment) {
+	if payment.RecipientID == 0 {
+		return
+	}
+
+	var sender models.User
+	if err := database.DB.First(&sender, payment.UserID).Error; err != nil {
+		return
+	}
+
+	services.NotifyUser(models.Notification{
+		UserID:  payment.RecipientID,
+		Type:    services.NotificationGiftReceived,
+		ActorID: &sender.ID,
+		Title:   "برات اشتراک هدیه اومده!",
+		Message: fmt.Sprintf("%s بهت اشتراک حرفه‌ای فنجون هدیه داد", utils.GetUserDisplayName(sender)),
+		Url:     "/premium",
+	})
+}
+
+func GetSubscriptionPlans(c *gin.Context) {
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: services.SubscriptionPlans})
+}
+
+func CreateSubscription(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var request struct {
+		Plan       string `json:"plan" binding:"required"`
+		CouponCode string `json:"couponCode"`
+		GiftTo     string `json:"giftTo"` // Recipient's ID or username
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	plan, ok := services.GetSubscriptionPlan(request.Plan)
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.SubscriptionPlanNotFound, Data: nil})
+		return
+	}
+
+	gateway := services.GetPaymentGateway()
+
+	payment := models.Payment{UserID: userId, Plan: plan.ID, Amount: plan.Price, Gateway: gateway.Name(), Status: "pending"}
+
+	if request.GiftTo != "" {
+		recipient, err := findUserByIdOrUsername(request.GiftTo)
+		if err != nil {
+			c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.UserNotFound, Data: nil})
+			return
+		}
+
+		if recipient.ID == userId {
+			c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GiftSelf, Data: nil})
+			return
+		}
+
+		payment.RecipientID = recipient.ID
+	}
+
+	if request.CouponCode != "" {
+		coupon, status, message := findRedeemableCoupon(request.CouponCode, userId)
+		if status != http.StatusOK {
+			c.JSON(status, responses.ApiResponse{Status: status, Message: message, Data: nil})
+			return
+		}
+
+		if coupon.Type == "free_days" {
+			c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.CouponNotApplicable, Data: nil})
+			return
+		}
+
+		payment.CouponID = coupon.ID
+		payment.Discount = applyCouponDiscount(plan.Price, coupon)
+		payment.Amount = plan.Price - payment.Discount
+	}
+
+	// Fully discounted purchases never reach the gateway
+	if payment.Amount == 0 {
+		now := time.Now()
+		payment.Gateway = "coupon"
+		payment.Status = "paid"
+		payment.PaidAt = &now
+
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := createPayment(tx, &payment); err != nil {
+				return err
+			}
+
+			return fulfillPayment(tx, payment, plan)
+		})
+
+		if err != nil {
+			status, message := couponRedemptionFailure(err)
+			c.JSON(status, responses.ApiResponse{Status: status, Message: message, Data: nil})
+			return
+		}
+
+		notifyGiftRecipient(payment)
+
+		c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: map[string]any{
+			"paymentId":   payment.ID,
+			"redirectUrl": getAppBaseUrl() + "/premium?status=success",
+		}})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return createPayment(tx, &payment)
+	})
+
+	if err != nil {
+		status, message := couponRedemptionFailure(err)
+		c.JSON(status, responses.ApiResponse{Status: status, Message: message, Data: nil})
+		return
+	}
+
+	callbackUrl := fmt.Sprintf("%s/v1/subscriptions/callback?paymentId=%d", os.Getenv("API_BASE_URL"), payment.ID)
+
+	authority, redirectUrl, err := gateway.RequestPayment(payment.Amount, plan.Title, callbackUrl)
+	if err != nil {
+		fmt.Printf("Failed to request payment: %v\n", err)
+		failPayment(payment)
+		c.JSON(http.StatusBadGateway, responses.ApiResponse{Status: http.StatusBadGateway, Message: messages.PaymentFailed, Data: nil})
+		return
+	}
+
+	if err := database.DB.Model(&payment).Update("authority", authority).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: map[string]any{
+		"paymentId":   payment.ID,
+		"redirectUrl": redirectUrl,
+	}})
+}
+
+// VerifySubscriptionPayment is the gateway callback; the user's browser lands here and is redirected back to the app.
+func VerifySubscriptionPayment(c *gin.Context) {
+	resultUrl := getAppBaseUrl() + "/premium?status="
+
+	paymentId, err := strconv.ParseUint(c.Query("paymentId"), 10, 32)
+	if err != nil {
+		c.Redirect(http.StatusFound, resultUrl+"failed")
+		return
+	}
+
+	var payment models.Payment
+	if err := database.DB.Where("id = ? AND authority = ?", paymentId, c.Query("Authority")).First(&payment).Error; err != nil {
+		c.Redirect(http.StatusFound, resultUrl+"failed")
+		return
+	}
+
+	if payment.Status == "paid" {
+		c.Redirect(http.StatusFound, resultUrl+"success")
+		return
+	}
+
+	if payment.Status != "pending" || c.Query("Status") != "OK" {
+		failPayment(payment)
+		c.Redirect(http.StatusFound, resultUrl+"failed")
+		return
+	}
+
+	plan, ok := services.GetSubscriptionPlan(payment.Plan)
+	if !ok {
+		c.Redirect(http.StatusFound, resultUrl+"failed")
+		return
+	}
+
+	gateway, ok := services.GetPaymentGatewayByName(payment.Gateway)
+	if !ok {
+		fmt.Printf("Unknown gateway %q of payment %d\n", payment.Gateway, payment.ID)
+		c.Redirect(http.StatusFound, resultUrl+"failed")
+		return
+	}
+
+	refId, err := gateway.VerifyPayment(payment.Authority, payment.Amount)
+	if err != nil {
+		fmt.Printf("Failed to verify payment %d: %v\n", payment.ID, err)
+		failPayment(payment)
+		c.Redirect(http.StatusFound, resultUrl+"failed")
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Guard against the callback being hit twice concurrently
+		result := tx.Model(&models.Payment{}).
+			Where("id = ? AND status = ?", payment.ID, "pending").
+			Updates(map[string]any{"status": "paid", "ref_id": refId, "paid_at": time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		return fulfillPayment(tx, payment, plan)
+	})
+
+	if err != nil {
+		fmt.Printf("Failed to activate subscription for payment %d: %v\n", payment.ID, err)
+		c.Redirect(http.StatusFound, resultUrl+"failed")
+		return
+	}
+
+	notifyGiftRecipient(payment)
+
+	c.Redirect(http.StatusFound, resultUrl+"success&refId="+refId)
+}
+
+func GetCurrentUserSubscription(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.UserNotFound, Data: nil})
+		return
+	}
+
+	var subscriptions []models.Subscription
+	if err := database.DB.Where("user_id = ? AND status = ? AND expires_at > ?", userId, "active", time.Now()).Order("starts_at ASC").Find(&subscriptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	var expiresAt *time.Time
+	if len(subscriptions) > 0 {
+		expiresAt = &subscriptions[len(subscriptions)-1].ExpiresAt
+	}
+
+	var lastPayment *models.Payment
+
+	var payment models.Payment
+	err = database.DB.Where("user_id = ?", userId).Order("id DESC").First(&payment).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	if err == nil {
+		lastPayment = &payment
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: map[string]any{
+		"isPremium":     user.IsPremium,
+		"expiresAt":     expiresAt,
+		"subscriptions": subscriptions,
+		"lastPayment":   lastPayment,
+	}})
+}
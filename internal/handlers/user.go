@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/freakingeek/fenjoon/internal/auth"
 	"github.com/freakingeek/fenjoon/internal/database"
@@ -15,14 +17,61 @@ import (
 	"github.com/freakingeek/fenjoon/internal/services"
 	"github.com/freakingeek/fenjoon/internal/utils"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// Old usernames keep redirecting to their owner (and stay unavailable to others) for this long.
+const usernameRedirectWindow = 30 * 24 * time.Hour
+
 func isFarsiText(text string) bool {
 	// Updated regex to match Persian characters and common punctuation, including the Persian comma (،)
 	re := regexp.MustCompile(`^[\p{Arabic}\s\x{200C}\x{0640}.,()\[\]؟!؛:،]+$`)
 	return re.MatchString(text)
 }
 
+func checkUsernameAvailability(username string, userId uint) (int, string) {
+	if !utils.IsValidUsername(username) {
+		return http.StatusBadRequest, messages.UsernameInvalid
+	}
+
+	if utils.IsReservedUsername(username) {
+		return http.StatusBadRequest, messages.UsernameReserved
+	}
+
+	var takenCount int64
+	if err := database.DB.Model(&models.User{}).Where("username = ? AND id != ?", username, userId).Count(&takenCount).Error; err != nil {
+		return http.StatusInternalServerError, messages.GeneralFailed
+	}
+
+	if takenCount > 0 {
+		return http.StatusConflict, messages.UsernameTaken
+	}
+
+	var heldCount int64
+	if err := database.DB.Model(&models.UsernameHistory{}).
+		Where("username = ? AND user_id != ?", username, userId).
+		Where("created_at > ?", time.Now().Add(-usernameRedirectWindow)).
+		Count(&heldCount).Error; err != nil {
+		return http.StatusInternalServerError, messages.GeneralFailed
+	}
+
+	if heldCount > 0 {
+		return http.StatusConflict, messages.UsernameTaken
+	}
+
+	return http.StatusOK, messages.UsernameAvailable
+}
+
+func findUserByIdOrUsername(value string) (models.User, error) {
+	var user models.User
+
+	if id, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return user, database.DB.First(&user, id).Error
+	}
+
+	return user, database.DB.Where("username = ?", utils.NormalizeUsername(value)).First(&user).Error
+}
+
 func GetCurrentUser(c *gin.Context) {
 	userId, err := auth.GetUserIdFromContext(c)
 	if err != nil {
@@ -41,6 +90,7 @@ func GetCurrentUser(c *gin.Context) {
 		Message: messages.GeneralSuccess,
 		Data: map[string]any{
 			"id":         user.ID,
+			"username":   user.Username,
 			"firstName":  user.FirstName,
 			"lastName":   user.LastName,
 			"nickname":   user.Nickname,
@@ -64,6 +114,7 @@ func UpdateCurrentUser(c *gin.Context) {
 		FirstName string `json:"firstName"`
 		LastName  string `json:"lastName"`
 		Nickname  string `json:"nickname"`
+		Username  string `json:"username"`
 		Bio       string `json:"bio" binding:"max=150"`
 	}
 
@@ -93,7 +144,46 @@ func UpdateCurrentUser(c *gin.Context) {
 	updates["nickname"] = strings.TrimSpace(request.Nickname)
 	updates["bio"] = strings.TrimSpace(request.Bio)
 
-	if err := database.DB.Model(&user).Updates(updates).Error; err != nil {
+	previousUsername := user.Username
+	username := utils.NormalizeUsername(request.Username)
+
+	if username != "" && username != previousUsername {
+		if status, message := checkUsernameAvailability(username, userId); status != http.StatusOK {
+			c.JSON(status, responses.ApiResponse{Status: status, Message: message, Data: nil})
+			return
+		}
+
+		updates["username"] = username
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if _, ok := updates["username"]; !ok {
+			return nil
+		}
+
+		// Reclaiming one of your own old usernames must not keep redirecting it
+		if err := tx.Where("user_id = ? AND username = ?", userId, username).Delete(&models.UsernameHistory{}).Error; err != nil {
+			return err
+		}
+
+		if previousUsername == "" {
+			return nil
+		}
+
+		return tx.Create(&models.UsernameHistory{UserID: userId, Username: previousUsername}).Error
+	})
+
+	// Someone else took the username between the availability check and the save
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		c.JSON(http.StatusConflict, responses.ApiResponse{Status: http.StatusConflict, Message: messages.UsernameTaken, Data: nil})
+		return
+	}
+
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
 		return
 	}
@@ -127,7 +217,7 @@ func GetCurrentUserStories(c *gin.Context) {
 
 	offset := (page - 1) * limit
 
-	query := database.DB.Model(&models.Story{}).Where("user_id = ?", userId)
+	query := database.DB.Model(&models.Story{}).Scopes(publishedStories).Where("user_id = ?", userId)
 
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
@@ -136,7 +226,7 @@ func GetCurrentUserStories(c *gin.Context) {
 	}
 
 	var stories []models.Story
-	if err := query.Preload("User").Order("id DESC").Limit(limit).Offset(offset).Find(&stories).Error; err != nil {
+	if err := query.Preload("User").Order(storiesByPublishedAt).Limit(limit).Offset(offset).Find(&stories).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
 		return
 	}
@@ -210,7 +300,7 @@ func GetUserPrivateStoriesCount(c *gin.Context) {
 	}
 
 	var privateStoriesCount int64
-	if err := database.DB.Model(&models.Story{}).Where("user_id = ? AND is_private = ?", userId, true).Count(&privateStoriesCount).Error; err != nil {
+	if err := database.DB.Model(&models.Story{}).Scopes(publishedStories).Where("user_id = ? AND is_private = ?", userId, true).Count(&privateStoriesCount).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
 		return
 	}
@@ -221,14 +311,37 @@ func GetUserPrivateStoriesCount(c *gin.Context) {
 		return
 	}
 
-	maxPrivateStories := 3
-	if user.IsPremium {
-		maxPrivateStories = -1 // -1 means unlimited
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: map[string]any{
+		"count": privateStoriesCount,
+		"max":   services.GetEntitlements(user).MaxPrivateStories,
+	}})
+}
+
+func GetCurrentUserEntitlements(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.UserNotFound, Data: nil})
+		return
+	}
+
+	var privateStoriesCount int64
+	if err := database.DB.Model(&models.Story{}).Scopes(publishedStories).Where("user_id = ? AND is_private = ?", userId, true).Count(&privateStoriesCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
 	}
 
 	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: map[string]any{
-		"count": privateStoriesCount,
-		"max":   maxPrivateStories,
+		"entitlements": services.GetEntitlements(user),
+		"premium":      services.GetPlanEntitlements("premium"),
+		"usage": map[string]any{
+			"privateStories": privateStoriesCount,
+		},
 	}})
 }
 
@@ -258,9 +371,32 @@ func GetCurrentUserBookmarks(c *gin.Context) {
 
 	offset := (page - 1) * limit
 
+	// folderId narrows the list down to one folder, "none" to the bookmarks that aren't in any folder
+	folderFilter := func(db *gorm.DB) *gorm.DB { return db }
+	switch folder := c.Query("folderId"); folder {
+	case "":
+	case "none":
+		folderFilter = func(db *gorm.DB) *gorm.DB { return db.Where("bookmarks.folder_id IS NULL") }
+	default:
+		folderId, err := strconv.ParseUint(folder, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.BookmarkFolderNotFound, Data: nil})
+			return
+		}
+
+		id := uint(folderId)
+		if err := checkBookmarkFolder(&id, userId); err != nil {
+			c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.BookmarkFolderNotFound, Data: nil})
+			return
+		}
+
+		folderFilter = func(db *gorm.DB) *gorm.DB { return db.Where("bookmarks.folder_id = ?", id) }
+	}
+
 	var total int64
 	if err := database.DB.
 		Table("bookmarks").
+		Scopes(folderFilter).
 		Where("user_id = ? AND deleted_at IS NULL", userId).
 		Count(&total).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, responses.ApiResponse{
@@ -274,6 +410,7 @@ func GetCurrentUserBookmarks(c *gin.Context) {
 	var stories []models.Story
 	if err := database.DB.
 		Joins("JOIN bookmarks ON bookmarks.story_id = stories.id").
+		Scopes(publishedStories, folderFilter).
 		Where("bookmarks.user_id = ? AND bookmarks.deleted_at IS NULL", userId).
 		Order("bookmarks.created_at DESC").
 		Limit(limit).
@@ -288,7 +425,27 @@ func GetCurrentUserBookmarks(c *gin.Context) {
 		return
 	}
 
+	storyIds := make([]uint, len(stories))
 	for i := range stories {
+		storyIds[i] = stories[i].ID
+	}
+
+	var bookmarks []models.Bookmark
+	if err := database.DB.Where("user_id = ? AND story_id IN ?", userId, storyIds).Find(&bookmarks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	bookmarksByStory := make(map[uint]models.Bookmark, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		bookmarksByStory[bookmark.StoryID] = bookmark
+	}
+
+	for i := range stories {
+		if bookmark, ok := bookmarksByStory[stories[i].ID]; ok {
+			stories[i].Bookmark = &bookmark
+		}
+
 		var likesCount int64
 		if err := database.DB.Model(&models.Like{}).Where("story_id = ?", stories[i].ID).Count(&likesCount).Error; err != nil {
 			c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.StoryNotFound, Data: nil})
@@ -389,11 +546,18 @@ func GetUserById(c *gin.Context) {
 		}
 	}
 
+	badges, err := services.GetUserBadges(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
 	c.JSON(http.StatusOK, responses.ApiResponse{
 		Status:  http.StatusOK,
 		Message: messages.GeneralSuccess,
 		Data: map[string]any{
 			"id":               user.ID,
+			"username":         user.Username,
 			"firstName":        user.FirstName,
 			"lastName":         user.LastName,
 			"nickname":         user.Nickname,
@@ -402,10 +566,71 @@ func GetUserById(c *gin.Context) {
 			"followersCount":   followersCount,
 			"followingsCount":  followingsCount,
 			"isFollowedByUser": isFollowedByUser,
+			"badges":           badges,
 		},
 	})
 }
 
+func GetUserByUsername(c *gin.Context) {
+	username := utils.NormalizeUsername(c.Param("username"))
+
+	if !utils.IsValidUsername(username) {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.UsernameInvalid, Data: nil})
+		return
+	}
+
+	if utils.IsReservedUsername(username) {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.UsernameReserved, Data: nil})
+		return
+	}
+
+	var user models.User
+	err := database.DB.Where("username = ?", username).First(&user).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	redirectTo := ""
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		var history models.UsernameHistory
+		if err := database.DB.
+			Where("username = ? AND created_at > ?", username, time.Now().Add(-usernameRedirectWindow)).
+			Order("id DESC").
+			First(&history).Error; err != nil {
+			c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.UsernameAvailable, Data: map[string]any{
+				"available":  true,
+				"redirectTo": nil,
+				"user":       nil,
+			}})
+			return
+		}
+
+		if err := database.DB.First(&user, history.UserID).Error; err != nil {
+			c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.UserNotFound, Data: nil})
+			return
+		}
+
+		redirectTo = user.Username
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.UsernameTaken, Data: map[string]any{
+		"available":  false,
+		"redirectTo": redirectTo,
+		"user": map[string]any{
+			"id":         user.ID,
+			"username":   user.Username,
+			"firstName":  user.FirstName,
+			"lastName":   user.LastName,
+			"nickname":   user.Nickname,
+			"bio":        user.Bio,
+			"isVerified": user.IsVerified,
+			"isPremium":  user.IsPremium,
+			"profileUrl": utils.GetUserProfileUrl(user),
+		},
+	}})
+}
+
 func GetUserPublicStories(c *gin.Context) {
 	userId, _ := auth.GetUserIdFromContext(c)
 
@@ -434,7 +659,7 @@ func GetUserPublicStories(c *gin.Context) {
 
 	offset := (page - 1) * limit
 
-	query := database.DB.Model(&models.Story{}).Where("user_id = ? AND is_private = ?", targetUserId, false)
+	query := database.DB.Model(&models.Story{}).Scopes(publishedStories).Where("user_id = ? AND is_private = ?", targetUserId, false)
 
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
@@ -443,7 +668,7 @@ func GetUserPublicStories(c *gin.Context) {
 	}
 
 	var stories []models.Story
-	if err := query.Preload("User").Order("id DESC").Limit(limit).Offset(offset).Find(&stories).Error; err != nil {
+	if err := query.Preload("User").Order(storiesByPublishedAt).Limit(limit).Offset(offset).Find(&stories).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
 		return
 	}
@@ -621,28 +846,43 @@ func FollowUser(c *gin.Context) {
 	}
 
 	follow := models.Follow{FollowerID: uint(userId), FollowingID: uint(followingUserId)}
-	if err := database.DB.Create(&follow).First(&follow, follow.ID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
-		return
+
+	// Attribute the follow to the story it came from, for the author's analytics
+	if storyId, err := strconv.ParseUint(c.Query("storyId"), 10, 32); err == nil {
+		var story models.Story
+		if err := database.DB.Where("id = ? AND user_id = ?", storyId, followingUserId).First(&story).Error; err == nil {
+			follow.StoryID = &story.ID
+		}
 	}
 
 	var user models.User
-	if err := database.DB.First(&user, userId).Error; err == nil {
-		text := fmt.Sprintf("%s از حالا دنبالت میکنه!", utils.GetUserDisplayName(user))
+	if err := database.DB.First(&user, userId).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.UserNotFound, Data: nil})
+		return
+	}
 
-		notification := models.Notification{UserID: uint(followingUserId), Title: "دنبال کننده جدید داری!", Message: text, Url: fmt.Sprintf("/author/%d", userId)}
-		if err := services.SendInAppNotification(notification); err != nil {
-			fmt.Printf("Failed to send in-app notification: %v\n", err)
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&follow).First(&follow, follow.ID).Error; err != nil {
+			return err
 		}
 
-		var pushToken models.PushToken
-		if err := database.DB.Where("user_id = ?", followingUserId).First(&pushToken).Error; err == nil {
-			if err := services.SendPushNotification([]string{pushToken.Token}, text); err != nil {
-				fmt.Printf("Failed to send push notification: %v\n", err)
-			}
-		}
+		return services.EnqueueNotification(tx, models.Notification{
+			UserID:  uint(followingUserId),
+			Type:    services.NotificationNewFollower,
+			ActorID: &user.ID,
+			Title:   "دنبال کننده جدید داری!",
+			Message: fmt.Sprintf("%s از حالا دنبالت میکنه!", utils.GetUserDisplayName(user)),
+			Url:     utils.GetUserProfileUrl(user),
+		})
+	})
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
 	}
 
+	go services.EvaluateBadges(services.BadgeEventFollowReceived, uint(followingUserId))
+
 	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: true})
 }
 
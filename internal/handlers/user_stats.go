@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/auth"
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/messages"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/responses"
+	"github.com/freakingeek/fenjoon/internal/services"
+	"github.com/freakingeek/fenjoon/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const userStatsCacheTTL = 10 * time.Minute
+
+// userStatsPeriods maps the selectable periods to their length in days, 0 meaning all time.
+var userStatsPeriods = map[string]int{"7d": 7, "30d": 30, "90d": 90, "365d": 365, "all": 0}
+
+func buildUserStats(userId uint, periodDays int) (map[string]any, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	since := time.Time{}
+	if periodDays > 0 {
+		since = today.AddDate(0, 0, -(periodDays - 1))
+	}
+
+	var texts []string
+	if err := database.DB.Model(&models.Story{}).Scopes(publishedStories).
+		Where("user_id = ? AND COALESCE(published_at, created_at) >= ?", userId, since).
+		Pluck("text", &texts).Error; err != nil {
+		return nil, err
+	}
+
+	words, characters := 0, 0
+	for _, text := range texts {
+		words += utils.CountWords(text)
+		characters += utils.CountCharacters(text)
+	}
+
+	userStories := database.DB.Model(&models.Story{}).Scopes(publishedStories).Where("user_id = ?", userId).Select("id")
+
+	var likesCount int64
+	if err := database.DB.Model(&models.Like{}).
+		Where("story_id IN (?) AND user_id <> ? AND created_at >= ?", userStories, userId, since).
+		Count(&likesCount).Error; err != nil {
+		return nil, err
+	}
+
+	var commentsCount int64
+	if err := database.DB.Model(&models.Comment{}).
+		Where("story_id IN (?) AND user_id <> ? AND created_at >= ?", userStories, userId, since).
+		Count(&commentsCount).Error; err != nil {
+		return nil, err
+	}
+
+	var followersGained int64
+	if err := database.DB.Model(&models.Follow{}).Where("following_id = ? AND created_at >= ?", userId, since).Count(&followersGained).Error; err != nil {
+		return nil, err
+	}
+
+	var followersCount int64
+	if err := database.DB.Model(&models.Follow{}).Where("following_id = ?", userId).Count(&followersCount).Error; err != nil {
+		return nil, err
+	}
+
+	var popular []struct {
+		StoryID    uint
+		LikesCount int64
+	}
+
+	if err := database.DB.Model(&models.Like{}).
+		Select("story_id, COUNT(*) AS likes_count").
+		Where("story_id IN (?) AND user_id <> ? AND created_at >= ?", userStories, userId, since).
+		Group("story_id").
+		Order("likes_count DESC").
+		Limit(5).
+		Scan(&popular).Error; err != nil {
+		return nil, err
+	}
+
+	storyIds := make([]uint, len(popular))
+	for i, row := range popular {
+		storyIds[i] = row.StoryID
+	}
+
+	var stories []models.Story
+	if len(storyIds) > 0 {
+		if err := database.DB.Preload("User").Scopes(publishedStories).Where("id IN ?", storyIds).Find(&stories).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	storiesById := make(map[uint]models.Story, len(stories))
+	for _, story := range stories {
+		storiesById[story.ID] = story
+	}
+
+	// Kept in the order of their likes
+	popularStories := []models.Story{}
+	for _, row := range popular {
+		story, ok := storiesById[row.StoryID]
+		if !ok {
+			continue
+		}
+
+		story.LikesCount = uint(row.LikesCount)
+		popularStories = append(popularStories, story)
+	}
+
+	// The streak calendar always covers the past year, whatever the selected period
+	writingDays, err := services.GetWritingDays(userId, today.AddDate(-1, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	currentStreak, longestStreak := services.GetWritingStreaks(writingDays, today)
+
+	// Long periods are grouped by month to keep the chart readable
+	bucket := "day"
+	if periodDays == 0 || periodDays > 90 {
+		bucket = "month"
+	}
+
+	var followerGrowth []struct {
+		Period time.Time `json:"period"`
+		Count  int64     `json:"count"`
+	}
+
+	if err := database.DB.Model(&models.Follow{}).
+		Select(fmt.Sprintf("DATE_TRUNC('%s', created_at) AS period, COUNT(*) AS count", bucket)).
+		Where("following_id = ? AND created_at >= ?", userId, since).
+		Group("period").
+		Order("period ASC").
+		Scan(&followerGrowth).Error; err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"totals": map[string]any{
+			"stories":         len(texts),
+			"words":           words,
+			"characters":      characters,
+			"likes":           likesCount,
+			"comments":        commentsCount,
+			"followersGained": followersGained,
+			"followers":       followersCount,
+		},
+		"popularStories": popularStories,
+		"streak": map[string]any{
+			"current":  currentStreak,
+			"longest":  longestStreak,
+			"calendar": writingDays,
+		},
+		"followerGrowth": map[string]any{
+			"bucket": bucket,
+			"points": followerGrowth,
+		},
+	}, nil
+}
+
+func GetCurrentUserStats(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	period := c.DefaultQuery("period", "30d")
+	periodDays, ok := userStatsPeriods[period]
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	cacheKey := fmt.Sprintf("user-stats:%d:%s", userId, period)
+
+	if cached, err := database.RedisClient.Get(context.Background(), cacheKey).Bytes(); err == nil {
+		var stats map[string]any
+		if err := json.Unmarshal(cached, &stats); err == nil {
+			c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: stats})
+			return
+		}
+	}
+
+	stats, err := buildUserStats(userId, periodDays)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	stats["period"] = period
+	stats["generatedAt"] = time.Now()
+
+	if encoded, err := json.Marshal(stats); err == nil {
+		if err := database.RedisClient.Set(context.Background(), cacheKey, encoded, userStatsCacheTTL).Err(); err != nil {
+			fmt.Printf("Failed to cache user stats: %v\n", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: stats})
+}
@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/auth"
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/messages"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/responses"
+	"github.com/freakingeek/fenjoon/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func RequestVerification(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var request struct {
+		FullName    string `json:"fullName" binding:"required,min=3,max=100"`
+		Description string `json:"description" binding:"required,min=20,max=500"`
+		Links       string `json:"links" binding:"max=500"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.UserNotFound, Data: nil})
+		return
+	}
+
+	if user.IsVerified {
+		c.JSON(http.StatusConflict, responses.ApiResponse{Status: http.StatusConflict, Message: messages.VerificationAlreadyVerified, Data: nil})
+		return
+	}
+
+	var pendingCount int64
+	if err := database.DB.Model(&models.VerificationRequest{}).Where("user_id = ? AND status = ?", userId, "pending").Count(&pendingCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	if pendingCount > 0 {
+		c.JSON(http.StatusConflict, responses.ApiResponse{Status: http.StatusConflict, Message: messages.VerificationAlreadyPending, Data: nil})
+		return
+	}
+
+	verificationRequest := models.VerificationRequest{
+		UserID:      userId,
+		FullName:    strings.TrimSpace(request.FullName),
+		Description: strings.TrimSpace(request.Description),
+		Links:       strings.TrimSpace(request.Links),
+		Status:      "pending",
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&verificationRequest).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.VerificationLog{RequestID: verificationRequest.ID, UserID: userId, ActorID: userId, Action: "applied"}).Error
+	})
+
+	// A double submit got its request in first
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		c.JSON(http.StatusConflict, responses.ApiResponse{Status: http.StatusConflict, Message: messages.VerificationAlreadyPending, Data: nil})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.VerificationRequested, Data: verificationRequest})
+}
+
+func GetCurrentUserVerification(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.UserNotFound, Data: nil})
+		return
+	}
+
+	var lastRequest *models.VerificationRequest
+
+	var verificationRequest models.VerificationRequest
+	err = database.DB.Where("user_id = ?", userId).Order("id DESC").First(&verificationRequest).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	if err == nil {
+		lastRequest = &verificationRequest
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: map[string]any{
+		"isVerified":  user.IsVerified,
+		"lastRequest": lastRequest,
+	}})
+}
+
+func GetVerificationRequests(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	if !user.IsAdmin {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	query := database.DB.Model(&models.VerificationRequest{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	var verificationRequests []models.VerificationRequest
+	if err := query.Preload("User").Order("id DESC").Limit(limit).Offset(offset).Find(&verificationRequests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{
+		Status:  http.StatusOK,
+		Message: messages.GeneralSuccess,
+		Data: map[string]any{
+			"requests": verificationRequests,
+			"pagination": map[string]any{
+				"total": total,
+				"page":  page,
+				"limit": limit,
+				"pages": int((total + int64(limit) - 1) / int64(limit)),
+			},
+		},
+	})
+}
+
+func GetVerificationRequest(c *gin.Context) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	if !user.IsAdmin {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	requestId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.VerificationNotFound, Data: nil})
+		return
+	}
+
+	var verificationRequest models.VerificationRequest
+	if err := database.DB.Preload("User").Where("id = ?", requestId).First(&verificationRequest).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.VerificationNotFound, Data: nil})
+		return
+	}
+
+	var logs []models.VerificationLog
+	if err := database.DB.Where("user_id = ?", verificationRequest.UserID).Order("id DESC").Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: map[string]any{
+		"request": verificationRequest,
+		"history": logs,
+	}})
+}
+
+func ApproveVerificationRequest(c *gin.Context) {
+	reviewVerificationRequest(c, "pending", "approved")
+}
+
+func RejectVerificationRequest(c *gin.Context) {
+	reviewVerificationRequest(c, "pending", "rejected")
+}
+
+func RevokeVerificationRequest(c *gin.Context) {
+	reviewVerificationRequest(c, "approved", "revoked")
+}
+
+func reviewVerificationRequest(c *gin.Context, fromStatus string, toStatus string) {
+	userId, err := auth.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, responses.ApiResponse{Status: http.StatusUnauthorized, Message: messages.GeneralUnauthorized, Data: nil})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	if !user.IsAdmin {
+		c.JSON(http.StatusForbidden, responses.ApiResponse{Status: http.StatusForbidden, Message: messages.GeneralAccessDenied, Data: nil})
+		return
+	}
+
+	var request struct {
+		Notes string `json:"notes" binding:"max=500"`
+	}
+
+	// Notes are optional, so is the body
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.GeneralBadRequest, Data: nil})
+		return
+	}
+
+	requestId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ApiResponse{Status: http.StatusBadRequest, Message: messages.VerificationNotFound, Data: nil})
+		return
+	}
+
+	var verificationRequest models.VerificationRequest
+	if err := database.DB.Where("id = ?", requestId).First(&verificationRequest).Error; err != nil {
+		c.JSON(http.StatusNotFound, responses.ApiResponse{Status: http.StatusNotFound, Message: messages.VerificationNotFound, Data: nil})
+		return
+	}
+
+	notReviewableMessage := messages.VerificationNotPending
+	if fromStatus == "approved" {
+		notReviewableMessage = messages.VerificationNotApproved
+	}
+
+	if verificationRequest.Status != fromStatus {
+		c.JSON(http.StatusConflict, responses.ApiResponse{Status: http.StatusConflict, Message: notReviewableMessage, Data: nil})
+		return
+	}
+
+	verificationRequest.Status = toStatus
+	verificationRequest.ReviewedAt = time.Now()
+	verificationRequest.ReviewedBy = userId
+	verificationRequest.ReviewNotes = strings.TrimSpace(request.Notes)
+
+	errAlreadyReviewed := errors.New("verification request was reviewed meanwhile")
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Only one of two admins reviewing the same request at once gets through
+		result := tx.Model(&models.VerificationRequest{}).Where("id = ? AND status = ?", verificationRequest.ID, fromStatus).Updates(map[string]any{
+			"status":       verificationRequest.Status,
+			"reviewed_at":  verificationRequest.ReviewedAt,
+			"reviewed_by":  verificationRequest.ReviewedBy,
+			"review_notes": verificationRequest.ReviewNotes,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return errAlreadyReviewed
+		}
+
+		if toStatus != "rejected" {
+			if err := tx.Model(&models.User{}).Where("id = ?", verificationRequest.UserID).Update("is_verified", toStatus == "approved").Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&models.VerificationLog{
+			RequestID: verificationRequest.ID,
+			UserID:    verificationRequest.UserID,
+			ActorID:   userId,
+			Action:    toStatus,
+			Notes:     verificationRequest.ReviewNotes,
+		}).Error
+	})
+
+	if errors.Is(err, errAlreadyReviewed) {
+		c.JSON(http.StatusConflict, responses.ApiResponse{Status: http.StatusConflict, Message: notReviewableMessage, Data: nil})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, responses.ApiResponse{Status: http.StatusInternalServerError, Message: messages.GeneralFailed, Data: nil})
+		return
+	}
+
+	var title, text string
+	switch toStatus {
+	case "approved":
+		title = "حسابت تایید شد!"
+		text = "تبریک! از حالا نشان تایید کنار اسمت نمایش داده میشه"
+	case "rejected":
+		title = "درخواست تاییدت رد شد"
+		text = "درخواست تایید حسابت بررسی شد ولی فعلا تایید نشد"
+	case "revoked":
+		title = "نشان تایید حسابت برداشته شد"
+		text = "نشان تایید حسابت توسط پشتیبانی برداشته شد"
+	}
+
+	if verificationRequest.ReviewNotes != "" {
+		text = fmt.Sprintf("%s: %s", text, verificationRequest.ReviewNotes)
+	}
+
+	services.NotifyUser(models.Notification{UserID: verificationRequest.UserID, Type: services.NotificationVerificationUpdated, Title: title, Message: text, Url: "/settings/verification"})
+
+	c.JSON(http.StatusOK, responses.ApiResponse{Status: http.StatusOK, Message: messages.GeneralSuccess, Data: verificationRequest})
+}
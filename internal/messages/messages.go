@@ -22,12 +22,60 @@ const (
 	StoryAlreadyLiked      = "این داستان رو قبلا لایک کردید"
 	StoryAlreadyBookmarked = "این داستان رو قبلا ذخیره کردید"
 	StoryCharLimit         = "داستان باید حداقل ۲۵ و حداکثر ۲۵۰ حرف باشد"
+	StoryCharLimitFormat   = "داستان باید حداقل ۲۵ و حداکثر %s حرف باشد"
 	StoryLiked             = "از این داستان خوشت اومد"
 	StoryDisliked          = "با این داستان حال نکردی"
 	StoryShareLimit        = "قبلا این داستان رو به اشتراک گذاشتی"
+	StoryContinuationsOff  = "نویسنده امکان ادامه دادن این داستان رو بسته"
 	// StoryMinCharLimit = "داستان باید حداقل شامل ۲۵ حرف باشد"
 	// StoryMaxCharLimit = "داستان می‌تواند نهایتا شامل ۲۵۶ حرف باشد"
 
+	DraftNotFound        = "پیش‌نویسی یافت نشد"
+	DraftSaved           = "پیش‌نویس ذخیره شد"
+	DraftDeleted         = "پیش‌نویس با موفقیت حذف شد"
+	DraftLimit           = "به سقف تعداد پیش‌نویس‌ها رسیدی، برای ذخیره پیش‌نویس‌های بیشتر اکانت حرفه‌ای تهیه کن"
+	DraftCharLimitFormat = "پیش‌نویس می‌تواند نهایتا شامل %s حرف باشد"
+
+	ScheduledStoryNotFound   = "داستان زمان‌بندی‌شده‌ای یافت نشد"
+	StoryScheduled           = "داستان زمان‌بندی شد و سر وقتش منتشر می‌شه"
+	StoryScheduleCanceled    = "زمان‌بندی لغو شد و داستان به پیش‌نویس‌ها برگشت"
+	StoryScheduleInvalidTime = "زمان انتشار باید بین الان تا ۳۰ روز آینده باشد"
+
+	CollectionNotFound        = "مجموعه‌ای یافت نشد"
+	CollectionCreated         = "مجموعه با موفقیت ساخته شد"
+	CollectionEdited          = "مجموعه با موفقیت ویرایش شد"
+	CollectionDeleted         = "مجموعه با موفقیت حذف شد"
+	CollectionStoryAdded      = "داستان به مجموعه اضافه شد"
+	CollectionStoryRemoved    = "داستان از مجموعه حذف شد"
+	CollectionStoryTaken      = "این داستان قبلا به یه مجموعه اضافه شده"
+	CollectionOrderInvalid    = "ترتیب داستان‌ها باید شامل همه داستان‌های مجموعه باشد"
+	CollectionFollowed        = "این مجموعه رو دنبال کردی، قسمت‌های تازه‌اش رو خبرت می‌کنیم"
+	CollectionUnfollowed      = "دیگه این مجموعه رو دنبال نمی‌کنی"
+	CollectionAlreadyFollowed = "این مجموعه رو قبلا دنبال کردی"
+
+	BookmarkFolderNotFound = "پوشه‌ای یافت نشد"
+	BookmarkFolderCreated  = "پوشه با موفقیت ساخته شد"
+	BookmarkFolderEdited   = "پوشه با موفقیت ویرایش شد"
+	BookmarkFolderDeleted  = "پوشه حذف شد و داستان‌هاش به ذخیره‌شده‌ها برگشتن"
+	BookmarkFolderLimit    = "به سقف تعداد پوشه‌ها رسیدی، برای ساخت پوشه‌های بیشتر اکانت حرفه‌ای تهیه کن"
+	BookmarkNotFound       = "این داستان رو ذخیره نکردید"
+	BookmarkEdited         = "داستان ذخیره‌شده با موفقیت ویرایش شد"
+	ReadingListNotFound    = "فهرست خوانشی یافت نشد"
+
+	ContestNotFound          = "مسابقه‌ای یافت نشد"
+	ContestInvalidDates      = "تاریخ‌های شروع، پایان ارسال و پایان رای‌گیری باید به ترتیب باشند"
+	ContestSubmissionsClosed = "زمان ارسال داستان برای این مسابقه نیست"
+	ContestEntrySubmitted    = "داستانت در مسابقه ثبت شد"
+	ContestEntryLimit        = "توی هر مسابقه فقط یه داستان می‌تونی بفرستی"
+	ContestStoryTooOld       = "فقط داستان‌هایی که بعد از شروع مسابقه نوشته شدن قابل ارسالن"
+	ContestEntryNotFound     = "این داستان در مسابقه شرکت نکرده"
+	ContestEntryTaken        = "این داستان قبلا در مسابقه‌ای شرکت داده شده"
+	ContestVotingClosed      = "زمان رای‌گیری این مسابقه نیست"
+	ContestAlreadyVoted      = "توی این مسابقه قبلا رای دادی"
+	ContestVoteOwnEntry      = "نمی‌تونی به داستان خودت رای بدی"
+	ContestVoterTooNew       = "حساب‌های تازه نمی‌تونن رای بدن، چند روز دیگه دوباره امتحان کن"
+	ContestVoted             = "رای‌ات ثبت شد"
+
 	CommentNotFound     = "نقدی یافت نشد"
 	CommentEdited       = "نقد با موفقیت ویرایش شد"
 	CommentLiked        = "از این نقد خوشت اومد"
@@ -42,5 +90,60 @@ const (
 	UserAlreadyFollowed = "این کاربر را قبلا دنبال کرده‌اید"
 	UserFollowSelf      = "نمی‌تونید خودتون رو دنبال کنید!"
 
+	UsernameInvalid   = "نام کاربری باید بین ۳ تا ۳۰ کاراکتر و فقط شامل حروف انگلیسی، اعداد و _ باشد"
+	UsernameReserved  = "این نام کاربری قابل استفاده نیست"
+	UsernameTaken     = "این نام کاربری قبلا انتخاب شده"
+	UsernameAvailable = "این نام کاربری آزاد است"
+
 	ReportNotFound = "گزارشی یافت نشد"
+
+	WebPushEndpointInvalid = "این مرورگر از اعلان‌ها پشتیبانی نمی‌کنه"
+
+	NotificationTypeInvalid   = "نوع اعلان انتخاب شده معتبر نیست"
+	NotificationSettingsSaved = "تنظیمات اعلان‌ها ذخیره شد"
+	QuietHoursInvalid         = "ساعت شروع و پایان سکوت باید به شکل ۲۳:۰۰ باشد"
+	QuietHoursSaved           = "ساعت سکوت ذخیره شد"
+	TimezoneInvalid           = "منطقه زمانی انتخاب شده معتبر نیست"
+
+	BroadcastNotFound        = "اطلاع‌رسانی یافت نشد"
+	BroadcastChannelRequired = "حداقل یکی از اعلان درون برنامه یا پوش رو انتخاب کنید"
+	BroadcastSegmentInvalid  = "مخاطبان انتخاب شده معتبر نیستند"
+	BroadcastScheduleInvalid = "زمان ارسال نمی‌تونه در گذشته باشه"
+	BroadcastNotCancellable  = "ارسال این اطلاع‌رسانی تموم شده و قابل لغو نیست"
+	BroadcastCreated         = "اطلاع‌رسانی ثبت شد"
+	BroadcastCancelled       = "اطلاع‌رسانی لغو شد"
+
+	WeeklyDigestTitle = "خلاصه هفته‌ات در فنجون"
+	// WeeklyDigestTemplate is a text/template of the weekly digest's message, fa writes numbers in Persian digits
+	WeeklyDigestTemplate = `{{if .TopStories}}داستان‌های برتر این هفته از نویسنده‌هایی که دنبال می‌کنی:
+{{range .TopStories}}• «{{.Excerpt}}» از {{.Author}}
+{{end}}{{end}}{{if .Views}}داستان‌هات این هفته {{fa .Views}} بار خونده شدن
+{{end}}{{if .Likes}}{{fa .Likes}} بار از داستان‌هات خوششون اومد
+{{end}}{{if .Comments}}{{fa .Comments}} نقد تازه روی داستان‌هات نوشته شد
+{{end}}{{if .NewFollowers}}{{fa .NewFollowers}} نفر تازه دنبالت کردن
+{{end}}`
+
+	SubscriptionPlanNotFound = "طرح اشتراک انتخاب شده معتبر نیست"
+	PaymentNotFound          = "پرداختی یافت نشد"
+	PaymentFailed            = "پرداخت موفقیت آمیز نبود"
+	GiftSelf                 = "نمی‌تونید به خودتون اشتراک هدیه بدید!"
+
+	CouponNotFound      = "کد تخفیف معتبر نیست"
+	CouponExpired       = "مهلت استفاده از این کد تخفیف تموم شده"
+	CouponUsageLimit    = "ظرفیت استفاده از این کد تخفیف تکمیل شده"
+	CouponAlreadyUsed   = "قبلا از این کد تخفیف استفاده کردید"
+	CouponNotApplicable = "این کد تخفیف برای این خرید قابل استفاده نیست"
+	CouponRedeemed      = "کد هدیه با موفقیت فعال شد"
+	CouponCodeTaken     = "کد تخفیف دیگری با این عنوان وجود دارد"
+
+	ReferralInvited         = "دعوت‌نامه با موفقیت ثبت شد"
+	ReferralPhoneRegistered = "این شماره قبلا در فنجون ثبت‌نام کرده"
+	ReferralAlreadyInvited  = "این شماره قبلا دعوت شده"
+
+	VerificationRequested       = "درخواست تایید حساب با موفقیت ثبت شد"
+	VerificationAlreadyVerified = "حساب شما قبلا تایید شده"
+	VerificationAlreadyPending  = "درخواست قبلی شما در حال بررسی است"
+	VerificationNotFound        = "درخواست تاییدی یافت نشد"
+	VerificationNotPending      = "این درخواست قبلا بررسی شده"
+	VerificationNotApproved     = "این درخواست تایید نشده که قابل لغو باشد"
 )
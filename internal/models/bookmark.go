@@ -10,6 +10,8 @@ type Bookmark struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
 	StoryID   uint           `gorm:"not null" json:"-"`
 	UserID    uint           `gorm:"not null" json:"-"`
+	FolderID  *uint          `gorm:"index" json:"folderId"` // Nil for bookmarks that aren't in any folder
+	Note      string         `gorm:"type:varchar(512);not null;default:''" json:"note"`
 	CreatedAt time.Time      `json:"createdAt"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
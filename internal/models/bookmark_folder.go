@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BookmarkFolder groups a user's bookmarks. Public folders are shared as reading lists.
+type BookmarkFolder struct {
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	UserID           uint           `gorm:"not null;index" json:"-"`
+	User             User           `gorm:"foreignKey:UserID" json:"user"`
+	Title            string         `gorm:"type:varchar(100);not null" json:"title"`
+	Description      string         `gorm:"type:varchar(512);not null;default:''" json:"description"`
+	IsPublic         bool           `gorm:"default:false" json:"isPublic"`
+	BookmarksCount   uint           `gorm:"-" json:"bookmarksCount"`
+	IsEditableByUser bool           `gorm:"-" json:"isEditableByUser"`
+	CreatedAt        time.Time      `json:"createdAt"`
+	UpdatedAt        time.Time      `json:"-"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+}
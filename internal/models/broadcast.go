@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// Broadcast is an announcement from the admins to a segment of users. Recipients are queued in ID order, a batch at a time.
+type Broadcast struct {
+	ID              uint             `gorm:"primaryKey" json:"id"`
+	Title           string           `gorm:"type:varchar(100);not null;default:''" json:"title"`
+	Message         string           `gorm:"type:varchar(512);not null" json:"message"`
+	Url             string           `gorm:"type:varchar(256);not null;default:''" json:"url"`
+	InApp           bool             `gorm:"not null" json:"inApp"`
+	Push            bool             `gorm:"not null" json:"push"`
+	Segment         string           `gorm:"type:varchar(16);not null" json:"segment"` // "all", "premium", "active", "contest", "users"
+	ActiveDays      int              `gorm:"not null;default:0" json:"activeDays,omitempty"`
+	ContestID       *uint            `json:"contestId,omitempty"`
+	UserIDs         []uint           `gorm:"type:text;serializer:json" json:"userIds,omitempty"`
+	RatePerMinute   int              `gorm:"not null" json:"ratePerMinute"`                                     // Recipients queued per minute
+	Status          string           `gorm:"type:varchar(16);not null;default:'scheduled';index" json:"status"` // "scheduled", "sending", "completed", "cancelled"
+	ScheduledAt     time.Time        `gorm:"not null" json:"scheduledAt"`
+	LastUserID      uint             `gorm:"not null;default:0" json:"-"`
+	RecipientsCount int64            `gorm:"not null;default:0" json:"recipientsCount"` // Queued so far
+	StartedAt       *time.Time       `json:"startedAt"`
+	CompletedAt     *time.Time       `json:"completedAt"`
+	CreatedBy       uint             `gorm:"not null" json:"-"`
+	Report          *BroadcastReport `gorm:"-" json:"report,omitempty"`
+	CreatedAt       time.Time        `json:"createdAt"`
+	UpdatedAt       time.Time        `json:"-"`
+}
+
+// BroadcastReport counts a broadcast's deliveries per "<channel>:<outcome>", and its jobs still waiting.
+type BroadcastReport struct {
+	Outcomes map[string]int64 `json:"outcomes"`
+	Pending  int64            `json:"pending"`
+}
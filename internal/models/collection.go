@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Collection struct {
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	UserID           uint           `gorm:"not null;index" json:"-"`
+	User             User           `gorm:"foreignKey:UserID" json:"user"`
+	Title            string         `gorm:"type:varchar(100);not null" json:"title"`
+	Description      string         `gorm:"type:varchar(512);not null;default:''" json:"description"`
+	IsPrivate        bool           `gorm:"default:false" json:"isPrivate"`
+	StoriesCount     uint           `gorm:"-" json:"storiesCount"`
+	FollowersCount   uint           `gorm:"-" json:"followersCount"`
+	IsFollowedByUser bool           `gorm:"-" json:"isFollowedByUser"`
+	IsEditableByUser bool           `gorm:"-" json:"isEditableByUser"`
+	CreatedAt        time.Time      `json:"createdAt"`
+	UpdatedAt        time.Time      `json:"-"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// StorySeries places a story within the collection it belongs to.
+type StorySeries struct {
+	CollectionID    uint   `json:"collectionId"`
+	Title           string `json:"title"`
+	PreviousStoryID *uint  `json:"previousStoryId"`
+	NextStoryID     *uint  `json:"nextStoryId"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import (
+	"time"
+)
+
+type CollectionFollow struct {
+	ID           uint      `gorm:"primaryKey" json:"-"`
+	CollectionID uint      `gorm:"not null;uniqueIndex:idx_collection_follows_collection_user" json:"-"`
+	UserID       uint      `gorm:"not null;uniqueIndex:idx_collection_follows_collection_user" json:"-"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// CollectionStory is a story's place in a collection. A story belongs to at most one collection.
+type CollectionStory struct {
+	ID           uint      `gorm:"primaryKey" json:"-"`
+	CollectionID uint      `gorm:"not null;index" json:"collectionId"`
+	StoryID      uint      `gorm:"not null;uniqueIndex" json:"storyId"`
+	Position     int       `gorm:"not null" json:"position"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
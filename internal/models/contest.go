@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Contest struct {
+	ID                 uint           `gorm:"primaryKey" json:"id"`
+	Type               string         `gorm:"type:varchar(16);not null;default:'prompt'" json:"type"` // "prompt", "contest"
+	Title              string         `gorm:"type:varchar(100);not null" json:"title"`
+	Theme              string         `gorm:"type:varchar(512);not null" json:"theme"`
+	Rules              string         `gorm:"type:text;not null;default:''" json:"rules"`
+	StartsAt           time.Time      `gorm:"not null" json:"startsAt"`
+	SubmissionsEndAt   time.Time      `gorm:"not null" json:"submissionsEndAt"`
+	VotingEndsAt       *time.Time     `json:"votingEndsAt"` // Contests only
+	WinnersCount       int            `gorm:"not null;default:3" json:"winnersCount"`
+	ResultsAnnouncedAt *time.Time     `gorm:"index" json:"resultsAnnouncedAt"`
+	CreatedBy          uint           `gorm:"not null" json:"-"`
+	Phase              string         `gorm:"-" json:"phase"`
+	EntriesCount       uint           `gorm:"-" json:"entriesCount"`
+	CreatedAt          time.Time      `json:"createdAt"`
+	UpdatedAt          time.Time      `json:"-"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+type ContestEntry struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	ContestID     uint      `gorm:"not null;index" json:"contestId"`
+	StoryID       uint      `gorm:"not null;uniqueIndex" json:"-"`
+	Story         Story     `gorm:"foreignKey:StoryID" json:"story"`
+	UserID        uint      `gorm:"not null;index" json:"-"`
+	VotesCount    uint      `gorm:"not null;default:0" json:"votesCount"` // Filled in when results are announced
+	Rank          int       `gorm:"not null;default:0" json:"rank"`       // 1 for the winner, 0 if unranked
+	IsVotedByUser bool      `gorm:"-" json:"isVotedByUser"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
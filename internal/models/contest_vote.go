@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// ContestVote allows one vote per user in each contest.
+type ContestVote struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	ContestID uint      `gorm:"not null;uniqueIndex:idx_contest_votes_contest_user" json:"-"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_contest_votes_contest_user" json:"-"`
+	EntryID   uint      `gorm:"not null;index" json:"-"`
+	CreatedAt time.Time `json:"-"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Coupon struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	Code      string         `gorm:"type:varchar(32);not null;uniqueIndex" json:"code"`
+	Type      string         `gorm:"type:varchar(32);not null" json:"type"` // "percent", "fixed", "free_days"
+	Value     int64          `gorm:"not null" json:"value"`                 // Percent, Toman or days depending on Type
+	MaxUses   int            `gorm:"not null;default:0" json:"maxUses"`     // 0 means unlimited
+	UsedCount int            `gorm:"not null;default:0" json:"usedCount"`
+	ExpiresAt *time.Time     `json:"expiresAt"`
+	CreatedBy uint           `gorm:"not null" json:"createdBy"`
+	CreatedAt time.Time      `json:"createdAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+type CouponRedemption struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CouponID  uint      `gorm:"not null;uniqueIndex:idx_coupon_redemptions_coupon_user" json:"couponId"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_coupon_redemptions_coupon_user" json:"userId"`
+	PaymentID uint      `json:"paymentId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
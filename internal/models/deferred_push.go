@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// DeferredPush is a push held back during the user's quiet hours, sent in a digest at DeliverAt.
+type DeferredPush struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	UserID     uint      `gorm:"not null;index" json:"-"`
+	Channel    string    `gorm:"type:varchar(16);not null" json:"-"` // "push", "web_push"
+	Type       string    `gorm:"type:varchar(32);not null" json:"-"`
+	TargetType string    `gorm:"type:varchar(32);not null;default:''" json:"-"`
+	TargetID   *uint     `json:"-"`
+	Title      string    `gorm:"not null;default:''" json:"-"`
+	Message    string    `gorm:"not null;default:''" json:"-"`
+	Url        string    `gorm:"not null;default:''" json:"-"`
+	DeliverAt  time.Time `gorm:"not null;index" json:"-"`
+	CreatedAt  time.Time `json:"-"`
+}
@@ -10,6 +10,7 @@ type Follow struct {
 	ID          uint           `gorm:"primaryKey" json:"-"`
 	FollowerID  uint           `gorm:"not null" json:"-"`
 	FollowingID uint           `gorm:"not null" json:"-"`
+	StoryID     *uint          `gorm:"index" json:"-"` // Story the follower came from, if any
 	CreatedAt   time.Time      `json:"-"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 }
@@ -7,13 +7,21 @@ import (
 )
 
 type Notification struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	UserID    uint           `gorm:"not null" json:"-"`
-	Title     string         `gorm:"default ''" json:"title"`
-	Message   string         `gorm:"default ''" json:"message"`
-	IsRead    bool           `gorm:"default false" json:"isRead"`
-	Image     string         `gorm:"default ''" json:"image"`
-	Url       string         `gorm:"default ''" json:"url"`
-	CreatedAt time.Time      `json:"-"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	UserID       uint           `gorm:"not null" json:"-"`
+	Type         string         `gorm:"type:varchar(32);not null;default:'general';index" json:"type"`
+	ActorID      *uint          `json:"-"` // The user whose action caused the notification, if any. The latest one for grouped notifications.
+	Actor        *User          `gorm:"foreignKey:ActorID" json:"actor"`
+	ActorsCount  uint           `gorm:"not null;default:0" json:"actorsCount"`
+	LatestActors []User         `gorm:"-" json:"latestActors"`
+	TargetType   string         `gorm:"type:varchar(32);not null;default:''" json:"targetType"` // "story", "comment", "user", "collection" or "contest"
+	TargetID     *uint          `json:"targetId"`
+	Title        string         `gorm:"default ''" json:"title"`
+	Message      string         `gorm:"default ''" json:"message"`
+	IsRead       bool           `gorm:"default false" json:"isRead"`
+	Image        string         `gorm:"default ''" json:"image"`
+	Url          string         `gorm:"default ''" json:"url"`
+	LastActorAt  *time.Time     `json:"lastActorAt"` // When the latest actor was grouped into the notification, nil until one is
+	CreatedAt    time.Time      `json:"-"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 }
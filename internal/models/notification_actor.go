@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// NotificationActor is one of the users grouped into a notification, like one of the people who liked a story.
+type NotificationActor struct {
+	ID             uint      `gorm:"primaryKey" json:"-"`
+	NotificationID uint      `gorm:"not null;uniqueIndex:idx_notification_actors_notification_user" json:"-"`
+	UserID         uint      `gorm:"not null;uniqueIndex:idx_notification_actors_notification_user" json:"-"`
+	CreatedAt      time.Time `json:"-"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// NotificationJob delivers a notification on one channel. Jobs are queued in the same transaction as the action
+// that caused the notification and delivered by the notification worker.
+type NotificationJob struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Channel       string     `gorm:"type:varchar(16);not null" json:"channel"`                                                  // "in_app", "push"
+	Status        string     `gorm:"type:varchar(16);not null;default:'pending';index:idx_notification_jobs_due" json:"status"` // "pending", "processing", "delivered", "dead"
+	NextAttemptAt time.Time  `gorm:"not null;index:idx_notification_jobs_due" json:"nextAttemptAt"`
+	LockedUntil   *time.Time `json:"-"` // Lease of the worker processing the job, expired leases are picked up again
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	LastError     string     `gorm:"type:text;not null;default:''" json:"lastError"`
+	DeliveredAt   *time.Time `json:"deliveredAt"`
+	BroadcastID   *uint      `gorm:"index" json:"broadcastId"`
+
+	// The notification to deliver
+	UserID     uint   `gorm:"not null" json:"userId"`
+	Type       string `gorm:"type:varchar(32);not null" json:"type"`
+	ActorID    *uint  `json:"actorId"`
+	TargetType string `gorm:"type:varchar(32);not null;default:''" json:"targetType"`
+	TargetID   *uint  `json:"targetId"`
+	Title      string `gorm:"not null;default:''" json:"title"`
+	Message    string `gorm:"not null;default:''" json:"message"`
+	Image      string `gorm:"not null;default:''" json:"image"`
+	Url        string `gorm:"not null;default:''" json:"url"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"-"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// NotificationSetting is a user's choice of channels for one notification type. Types without a row are delivered on every channel.
+type NotificationSetting struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_notification_settings_user_type" json:"-"`
+	Type      string    `gorm:"type:varchar(32);not null;uniqueIndex:idx_notification_settings_user_type" json:"type"`
+	Title     string    `gorm:"-" json:"title"`
+	InApp     bool      `gorm:"not null" json:"inApp"`
+	Push      bool      `gorm:"not null" json:"push"`
+	UpdatedAt time.Time `json:"-"`
+}
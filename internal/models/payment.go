@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Payment struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	UserID      uint           `gorm:"not null;index" json:"-"`
+	Plan        string         `gorm:"type:varchar(32);not null" json:"plan"`
+	Amount      int64          `gorm:"not null" json:"amount"` // Toman, after discount
+	Discount    int64          `gorm:"not null;default:0" json:"discount"`
+	CouponID    uint           `json:"-"`
+	RecipientID uint           `json:"recipientId,omitempty"` // Set when the subscription is a gift
+	Gateway     string         `gorm:"type:varchar(32);not null" json:"gateway"`
+	Authority   string         `gorm:"type:varchar(128);index" json:"-"`
+	RefID       string         `gorm:"type:varchar(128)" json:"refId,omitempty"`
+	Status      string         `gorm:"type:varchar(32);not null;default:'pending'" json:"status"` // "pending", "paid", "failed"
+	PaidAt      *time.Time     `json:"paidAt,omitempty"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"-"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// PushTicket is an accepted push whose Expo receipt hasn't been checked yet.
+type PushTicket struct {
+	ID          uint      `gorm:"primaryKey" json:"-"`
+	TicketID    string    `gorm:"type:varchar(64);not null;uniqueIndex" json:"ticketId"`
+	PushTokenID uint      `gorm:"not null;index" json:"-"`
+	CreatedAt   time.Time `gorm:"index" json:"-"`
+}
@@ -6,10 +6,13 @@ import (
 	"gorm.io/gorm"
 )
 
+// PushToken is one device's Expo push token. A user gets pushes on every device they registered.
 type PushToken struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	UserID    uint           `gorm:"not null" json:"userId"`
-	Token     string         `gorm:"not null" json:"token"`
-	CreatedAt time.Time      `json:"-"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	UserID     uint           `gorm:"not null;index" json:"userId"`
+	Token      string         `gorm:"not null;uniqueIndex:idx_push_tokens_unique_token" json:"token"`
+	Platform   string         `gorm:"type:varchar(16);not null;default:''" json:"platform"` // "ios", "android" or "web"
+	LastSeenAt *time.Time     `json:"lastSeenAt"`
+	CreatedAt  time.Time      `json:"-"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
 }
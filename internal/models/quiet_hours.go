@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// QuietHours is a daily window, in the user's timezone, in which pushes are held back and sent as one digest once it ends.
+type QuietHours struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	UserID    uint      `gorm:"not null;uniqueIndex" json:"-"`
+	Enabled   bool      `gorm:"not null" json:"enabled"`
+	Start     string    `gorm:"column:start_time;type:varchar(5);not null" json:"start"` // "HH:MM"
+	End       string    `gorm:"column:end_time;type:varchar(5);not null" json:"end"`     // "HH:MM", before Start when the window spans midnight
+	Timezone  string    `gorm:"-" json:"timezone"`
+	UpdatedAt time.Time `json:"-"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Referral struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	ReferrerID     uint           `gorm:"not null;index" json:"-"`
+	ReferredUserID uint           `gorm:"not null;default:0;index:idx_referrals_referred_user,unique,where:referred_user_id <> 0" json:"referredUserId,omitempty"`
+	Phone          string         `gorm:"type:varchar(11);not null;index" json:"-"`
+	RewardDays     int            `gorm:"not null;default:0" json:"rewardDays"`
+	RewardedAt     *time.Time     `json:"rewardedAt"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+}
@@ -7,22 +7,33 @@ import (
 )
 
 type Story struct {
-	ID                 uint           `gorm:"primaryKey" json:"id"`
-	Text               string         `gorm:"type:varchar(256);not null" json:"text"`
-	UserID             uint           `gorm:"not null" json:"-"`
-	User               User           `gorm:"foreignKey:UserID" json:"user"`
-	Likes              []Like         `gorm:"foreignKey:StoryID" json:"-"`
-	Shares             []Share        `gorm:"foreignKey:StoryID" json:"-"`
-	Comments           []Comment      `gorm:"foreignKey:StoryID" json:"-"`
-	LikesCount         uint           `gorm:"-" json:"likesCount"`
-	SharesCount        uint           `gorm:"-" json:"sharesCount"`
-	CommentsCount      uint           `gorm:"-" json:"commentsCount"`
-	IsPrivate          bool           `gorm:"default:false" json:"isPrivate"`
-	IsLikedByUser      bool           `gorm:"-" json:"isLikedByUser"`
-	IsEditableByUser   bool           `gorm:"-" json:"isEditableByUser"`
-	IsPrivatableByUser bool           `gorm:"-" json:"isPrivatableByUser"`
-	IsDeletableByUser  bool           `gorm:"-" json:"isDeletableByUser"`
-	CreatedAt          time.Time      `json:"createdAt"`
-	UpdatedAt          time.Time      `json:"-"`
-	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                    uint           `gorm:"primaryKey" json:"id"`
+	Text                  string         `gorm:"type:varchar(512);not null" json:"text"`
+	UserID                uint           `gorm:"not null" json:"-"`
+	User                  User           `gorm:"foreignKey:UserID" json:"user"`
+	Likes                 []Like         `gorm:"foreignKey:StoryID" json:"-"`
+	Shares                []Share        `gorm:"foreignKey:StoryID" json:"-"`
+	Comments              []Comment      `gorm:"foreignKey:StoryID" json:"-"`
+	LikesCount            uint           `gorm:"-" json:"likesCount"`
+	SharesCount           uint           `gorm:"-" json:"sharesCount"`
+	CommentsCount         uint           `gorm:"-" json:"commentsCount"`
+	ViewsCount            uint           `gorm:"-" json:"viewsCount,omitempty"`
+	IsPrivate             bool           `gorm:"default:false" json:"isPrivate"`
+	IsEdited              bool           `gorm:"default:false" json:"isEdited"`
+	ParentID              *uint          `gorm:"index" json:"parentId"` // Set when the story continues another one
+	ContinuationsDisabled bool           `gorm:"default:false" json:"continuationsDisabled"`
+	ContinuationsCount    uint           `gorm:"-" json:"continuationsCount"`
+	Ancestry              []Story        `gorm:"-" json:"ancestry,omitempty"`                                       // From the root story down to the parent
+	Status                string         `gorm:"type:varchar(32);not null;default:'published';index" json:"status"` // "draft", "scheduled", "published"
+	PublishAt             *time.Time     `gorm:"index" json:"publishAt"`
+	PublishedAt           *time.Time     `gorm:"index" json:"publishedAt"` // Feeds are ordered by it, stories from before it existed fall back to CreatedAt
+	IsLikedByUser         bool           `gorm:"-" json:"isLikedByUser"`
+	IsEditableByUser      bool           `gorm:"-" json:"isEditableByUser"`
+	IsPrivatableByUser    bool           `gorm:"-" json:"isPrivatableByUser"`
+	IsDeletableByUser     bool           `gorm:"-" json:"isDeletableByUser"`
+	Series                *StorySeries   `gorm:"-" json:"series,omitempty"`
+	Bookmark              *Bookmark      `gorm:"-" json:"bookmark,omitempty"` // The viewer's own bookmark, in bookmark listings
+	CreatedAt             time.Time      `json:"createdAt"`
+	UpdatedAt             time.Time      `json:"-"`
+	DeletedAt             gorm.DeletedAt `gorm:"index" json:"-"`
 }
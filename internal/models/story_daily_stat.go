@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// StoryDailyStat holds the unique views and feed impressions of a story on one day, flushed from Redis.
+type StoryDailyStat struct {
+	ID          uint      `gorm:"primaryKey" json:"-"`
+	StoryID     uint      `gorm:"not null;uniqueIndex:idx_story_daily_stats_story_date" json:"-"`
+	Date        time.Time `gorm:"type:date;not null;uniqueIndex:idx_story_daily_stats_story_date" json:"date"`
+	Views       int64     `gorm:"not null;default:0" json:"views"`
+	Impressions int64     `gorm:"not null;default:0" json:"impressions"`
+	UpdatedAt   time.Time `json:"-"`
+}
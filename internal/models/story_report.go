@@ -16,6 +16,7 @@ type StoryReport struct {
 	ResolvedBy      uint           `json:"resolvedBy,omitempty"`
 	ResolutionNotes string         `gorm:"type:varchar(512);" json:"resolutionNotes,omitempty"`
 	Story           Story          `gorm:"foreignKey:StoryID" json:"story,omitempty"`
+	ReportedText    string         `gorm:"-" json:"reportedText,omitempty"` // Story text as it was when reported
 	CreatedAt       time.Time      `json:"createdAt"`
 	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
 }
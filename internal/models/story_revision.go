@@ -0,0 +1,12 @@
+package models
+
+import (
+	"time"
+)
+
+type StoryRevision struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	StoryID   uint      `gorm:"not null;index" json:"storyId"`
+	Text      string    `gorm:"type:varchar(512);not null" json:"text"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Subscription struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	UserID         uint           `gorm:"not null;index" json:"-"`
+	PaymentID      uint           `gorm:"not null" json:"-"`
+	Plan           string         `gorm:"type:varchar(32);not null" json:"plan"`
+	Status         string         `gorm:"type:varchar(32);not null;default:'active'" json:"status"` // "active", "expired"
+	StartsAt       time.Time      `gorm:"not null" json:"startsAt"`
+	ExpiresAt      time.Time      `gorm:"not null;index" json:"expiresAt"`
+	ReminderSentAt *time.Time     `json:"-"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"-"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+}
@@ -9,6 +9,7 @@ import (
 type User struct {
 	ID               uint           `gorm:"primaryKey" json:"id"`
 	Phone            string         `gorm:"varchar(11);<-:create" json:"-"`
+	Username         string         `gorm:"type:varchar(30);default:'';index:idx_users_username,unique,where:username <> ''" json:"username"`
 	Bio              string         `gorm:"varchar(100)" json:"bio"`
 	FollowersCount   uint           `gorm:"-" json:"followersCount"`
 	FollowingsCount  uint           `gorm:"-" json:"followingsCount"`
@@ -22,6 +23,8 @@ type User struct {
 	IsBot            bool           `gorm:"default:false" json:"isBot"`
 	IsAdmin          bool           `gorm:"default:false" json:"-"`
 	IsPremium        bool           `gorm:"default:false" json:"isPremium"`
+	Timezone         string         `gorm:"type:varchar(64);not null;default:'Asia/Tehran'" json:"-"`
+	ReferralCode     string         `gorm:"type:varchar(16);default:'';index:idx_users_referral_code,unique,where:referral_code <> ''" json:"-"`
 	CreatedAt        time.Time      `json:"createdAt"`
 	UpdatedAt        time.Time      `json:"-"`
 	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
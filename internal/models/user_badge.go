@@ -0,0 +1,12 @@
+package models
+
+import (
+	"time"
+)
+
+type UserBadge struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_user_badges_user_badge" json:"-"`
+	Badge     string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_user_badges_user_badge" json:"badge"`
+	CreatedAt time.Time `json:"awardedAt"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UsernameHistory keeps a user's previous usernames so old profile links can be redirected.
+type UsernameHistory struct {
+	ID        uint           `gorm:"primaryKey" json:"-"`
+	UserID    uint           `gorm:"not null;index" json:"-"`
+	Username  string         `gorm:"type:varchar(30);not null;index" json:"username"`
+	CreatedAt time.Time      `json:"changedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+type VerificationLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	RequestID uint      `gorm:"not null;index" json:"requestId"`
+	UserID    uint      `gorm:"not null;index" json:"userId"`
+	ActorID   uint      `gorm:"not null" json:"actorId"`
+	Action    string    `gorm:"type:varchar(64);not null" json:"action"` // "applied", "approved", "rejected", "revoked"
+	Notes     string    `gorm:"type:varchar(512)" json:"notes,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
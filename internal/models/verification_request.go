@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type VerificationRequest struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	UserID      uint           `gorm:"not null;index;index:idx_verification_requests_pending_user,unique,where:status = 'pending' AND deleted_at IS NULL" json:"userId"` // One pending request per user
+	User        User           `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	FullName    string         `gorm:"type:varchar(100);not null" json:"fullName"`
+	Description string         `gorm:"type:varchar(512);not null" json:"description"`
+	Links       string         `gorm:"type:varchar(512)" json:"links"`
+	Status      string         `gorm:"type:varchar(64);not null;default:'pending'" json:"status"` // "pending", "approved", "rejected", "revoked"
+	ReviewedAt  time.Time      `json:"reviewedAt,omitempty"`
+	ReviewedBy  uint           `json:"reviewedBy,omitempty"`
+	ReviewNotes string         `gorm:"type:varchar(512);" json:"reviewNotes,omitempty"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
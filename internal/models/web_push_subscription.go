@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebPushSubscription is a browser's Push API subscription, as the service worker's PushSubscription.toJSON() gives it.
+type WebPushSubscription struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	UserID     uint           `gorm:"not null;index" json:"-"`
+	Endpoint   string         `gorm:"type:text;not null;uniqueIndex" json:"endpoint"`
+	P256dh     string         `gorm:"type:varchar(128);not null" json:"-"` // The browser's public key, base64url
+	Auth       string         `gorm:"type:varchar(32);not null" json:"-"`  // The browser's auth secret, base64url
+	UserAgent  string         `gorm:"type:varchar(256);not null;default:''" json:"userAgent"`
+	LastSeenAt *time.Time     `json:"lastSeenAt"`
+	CreatedAt  time.Time      `json:"-"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+}
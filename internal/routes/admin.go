@@ -12,4 +12,27 @@ func AdminRoutes(r *gin.RouterGroup) {
 	v1.GET("/story-reports/:id", handlers.GetStoryReport)
 	v1.PUT("/story-reports/:id/resolve", handlers.ResolveStoryReport)
 	v1.PUT("/story-reports/:id/reject", handlers.RejectStoryReport)
+
+	v1.GET("/verification-requests", handlers.GetVerificationRequests)
+	v1.GET("/verification-requests/:id", handlers.GetVerificationRequest)
+	v1.PUT("/verification-requests/:id/approve", handlers.ApproveVerificationRequest)
+	v1.PUT("/verification-requests/:id/reject", handlers.RejectVerificationRequest)
+	v1.PUT("/verification-requests/:id/revoke", handlers.RevokeVerificationRequest)
+
+	v1.GET("/coupons", handlers.GetCoupons)
+	v1.POST("/coupons", handlers.CreateCoupon)
+	v1.DELETE("/coupons/:id", handlers.DeleteCoupon)
+
+	v1.POST("/contests", handlers.CreateContest)
+	v1.PUT("/contests/:id", handlers.UpdateContest)
+	v1.DELETE("/contests/:id", handlers.DeleteContest)
+
+	v1.GET("/notification-jobs", handlers.GetNotificationJobs)
+	v1.GET("/notification-jobs/stats", handlers.GetNotificationJobStats)
+	v1.POST("/notification-jobs/:id/retry", handlers.RetryNotificationJob)
+
+	v1.GET("/broadcasts", handlers.GetBroadcasts)
+	v1.POST("/broadcasts", handlers.CreateBroadcast)
+	v1.GET("/broadcasts/:id", handlers.GetBroadcast)
+	v1.POST("/broadcasts/:id/cancel", handlers.CancelBroadcast)
 }
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/freakingeek/fenjoon/internal/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+func BookmarkFolderRoutes(r *gin.RouterGroup) {
+	v1 := r.Group("/bookmark-folders")
+
+	v1.POST("", handlers.CreateBookmarkFolder)
+	v1.PUT(":id", handlers.UpdateBookmarkFolder)
+	v1.DELETE(":id", handlers.DeleteBookmarkFolder)
+}
+
+// ReadingListRoutes serves public bookmark folders to everyone.
+func ReadingListRoutes(r *gin.RouterGroup) {
+	v1 := r.Group("/reading-lists")
+
+	v1.GET(":id", handlers.GetReadingList)
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/freakingeek/fenjoon/internal/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+func CollectionRoutes(r *gin.RouterGroup) {
+	v1 := r.Group("/collections")
+
+	v1.POST("", handlers.CreateCollection)
+	v1.GET(":id", handlers.GetCollectionById)
+	v1.PUT(":id", handlers.UpdateCollection)
+	v1.DELETE(":id", handlers.DeleteCollection)
+
+	v1.POST(":id/stories", handlers.AddStoryToCollection)
+	v1.PUT(":id/stories", handlers.ReorderCollectionStories)
+	v1.DELETE(":id/stories/:storyId", handlers.RemoveStoryFromCollection)
+
+	v1.POST(":id/follow", handlers.FollowCollection)
+	v1.DELETE(":id/follow", handlers.UnfollowCollection)
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/freakingeek/fenjoon/internal/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+func ContestRoutes(r *gin.RouterGroup) {
+	v1 := r.Group("/contests")
+
+	v1.GET("", handlers.GetContests)
+	v1.GET(":id", handlers.GetContestById)
+	v1.GET(":id/entries", handlers.GetContestEntries)
+	v1.POST(":id/entries", handlers.SubmitContestEntry)
+	v1.POST(":id/entries/:entryId/votes", handlers.VoteContestEntry)
+}
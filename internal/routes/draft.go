@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/freakingeek/fenjoon/internal/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+func DraftRoutes(r *gin.RouterGroup) {
+	v1 := r.Group("/drafts")
+
+	v1.POST("", handlers.CreateDraft)
+	v1.GET("/:id", handlers.GetDraftById)
+	v1.PUT("/:id", handlers.UpdateDraft)
+	v1.DELETE("/:id", handlers.DeleteDraft)
+	v1.POST("/:id/publish", handlers.PublishDraft)
+}
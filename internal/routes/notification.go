@@ -9,7 +9,11 @@ func NotificationRoutes(r *gin.RouterGroup) {
 	v1 := r.Group("/notifications")
 
 	v1.GET("", handlers.GetUserNotifications)
+	v1.DELETE("", handlers.DeleteAllNotifications)
 	v1.GET(":id", handlers.GetNotificationById)
+	v1.DELETE(":id", handlers.DeleteNotification)
 	v1.GET("/unread-count", handlers.GetUserNotificationsUnreadCount)
+	v1.GET("/stream", handlers.StreamNotifications)
 	v1.PATCH("/read", handlers.MarkNotificationsAsRead)
+	v1.PATCH("/read-all", handlers.MarkAllNotificationsAsRead)
 }
@@ -10,4 +10,8 @@ func PushRoutes(r *gin.RouterGroup) {
 
 	v1.POST("/register", handlers.RegisterPushToken)
 	v1.DELETE("/unregister", handlers.UnregisterPushToken)
+
+	v1.GET("/web/vapid-public-key", handlers.GetVapidPublicKey)
+	v1.POST("/web/register", handlers.RegisterWebPushSubscription)
+	v1.DELETE("/web/unregister", handlers.UnregisterWebPushSubscription)
 }
@@ -10,6 +10,12 @@ func SetupRoutes(r *gin.Engine) {
 	PushRoutes(v1)
 	AdminRoutes(v1)
 	StoryRoutes(v1)
+	DraftRoutes(v1)
+	CollectionRoutes(v1)
+	BookmarkFolderRoutes(v1)
+	ReadingListRoutes(v1)
+	ContestRoutes(v1)
 	CommentRoutes(v1)
 	NotificationRoutes(v1)
+	SubscriptionRoutes(v1)
 }
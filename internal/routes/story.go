@@ -13,6 +13,8 @@ func StoryRoutes(r *gin.RouterGroup) {
 	v1.GET(":id", handlers.GetStoryById)
 	v1.PUT(":id", handlers.UpdateStory)
 	v1.DELETE(":id", handlers.DeleteStory)
+	v1.GET(":id/revisions", handlers.GetStoryRevisions)
+	v1.GET(":id/analytics", handlers.GetStoryAnalytics)
 
 	v1.GET(":id/likes", handlers.GetStoryLikers)
 	v1.POST(":id/likes", handlers.LikeStoryById)
@@ -27,9 +29,16 @@ func StoryRoutes(r *gin.RouterGroup) {
 	v1.POST(":id/reports", handlers.ReportStory)
 
 	v1.POST(":id/bookmarks", handlers.BookmarkStory)
+	v1.PUT(":id/bookmarks", handlers.UpdateBookmark)
 	v1.DELETE(":id/bookmarks", handlers.UnBookmarkStory)
 
 	v1.GET(":id/related-by-author", handlers.GetAuthorOtherStories)
 
 	v1.PATCH(":id/visibility", handlers.ChangeStoryVisibility)
+
+	v1.GET(":id/continuations", handlers.GetStoryContinuations)
+	v1.PATCH(":id/continuations", handlers.ChangeStoryContinuations)
+
+	v1.PATCH(":id/schedule", handlers.RescheduleStory)
+	v1.DELETE(":id/schedule", handlers.CancelScheduledStory)
 }
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/freakingeek/fenjoon/internal/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+func SubscriptionRoutes(r *gin.RouterGroup) {
+	v1 := r.Group("/subscriptions")
+
+	v1.GET("/plans", handlers.GetSubscriptionPlans)
+	v1.POST("", handlers.CreateSubscription)
+	v1.GET("/callback", handlers.VerifySubscriptionPayment)
+	v1.POST("/coupons/redeem", handlers.RedeemCoupon)
+}
@@ -11,12 +11,30 @@ func UserRoutes(r *gin.RouterGroup) {
 	v1.GET("/me", handlers.GetCurrentUser)
 	v1.PATCH("/me", handlers.UpdateCurrentUser)
 	v1.GET("/me/stories", handlers.GetCurrentUserStories) // All User stories (public + private)
+	v1.GET("/me/drafts", handlers.GetCurrentUserDrafts)
+	v1.GET("/me/scheduled-stories", handlers.GetCurrentUserScheduledStories)
 	v1.GET("/me/private-story-count", handlers.GetUserPrivateStoriesCount)
+	v1.GET("/me/entitlements", handlers.GetCurrentUserEntitlements)
+	v1.GET("/me/stats", handlers.GetCurrentUserStats)
 	v1.GET("/me/bookmarks", handlers.GetCurrentUserBookmarks)
+	v1.GET("/me/bookmark-folders", handlers.GetCurrentUserBookmarkFolders)
+	v1.GET("/me/notification-settings", handlers.GetCurrentUserNotificationSettings)
+	v1.PUT("/me/notification-settings", handlers.UpdateCurrentUserNotificationSettings)
+	v1.GET("/me/quiet-hours", handlers.GetCurrentUserQuietHours)
+	v1.PUT("/me/quiet-hours", handlers.UpdateCurrentUserQuietHours)
+	v1.GET("/me/verification", handlers.GetCurrentUserVerification)
+	v1.POST("/me/verification", handlers.RequestVerification)
+	v1.GET("/me/subscription", handlers.GetCurrentUserSubscription)
+	v1.GET("/me/referral", handlers.GetCurrentUserReferral)
+	v1.POST("/me/referral/invites", handlers.InviteByPhone)
+
+	v1.GET("/by-username/:username", handlers.GetUserByUsername)
 
 	v1.GET(":id", handlers.GetUserById)
 	v1.GET(":id/stories", handlers.GetUserPublicStories) // Public Stories
 	v1.GET(":id/comments", handlers.GetUserComments)     // Public Comments
+	v1.GET(":id/collections", handlers.GetUserCollections)
+	v1.GET(":id/reading-lists", handlers.GetUserReadingLists)
 
 	v1.POST(":id/follow", handlers.FollowUser)
 	v1.DELETE(":id/unfollow", handlers.UnfollowUser)
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Events badge rules are evaluated on
+const (
+	BadgeEventStoryCreated   = "story_created"
+	BadgeEventLikeReceived   = "like_received"
+	BadgeEventFollowReceived = "follow_received"
+)
+
+type Badge struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// Event the rule is checked on. Badges without one, like contest wins, are awarded directly.
+	Event  string                          `json:"-"`
+	Earned func(userId uint) (bool, error) `json:"-"`
+}
+
+type AwardedBadge struct {
+	Badge
+	AwardedAt time.Time `json:"awardedAt"`
+}
+
+var Badges = []Badge{
+	{
+		ID:          "first_story",
+		Title:       "اولین داستان",
+		Description: "اولین داستانش رو توی فنجون منتشر کرده",
+		Event:       BadgeEventStoryCreated,
+		Earned: func(userId uint) (bool, error) {
+			return countAtLeast(database.DB.Model(&models.Story{}).Where("user_id = ? AND status = ?", userId, "published"), 1)
+		},
+	},
+	{
+		ID:          "streak_7",
+		Title:       "هفت روز پشت سر هم",
+		Description: "هفت روز پشت سر هم داستان نوشته",
+		Event:       BadgeEventStoryCreated,
+		Earned:      hasWritingStreak(7),
+	},
+	{
+		ID:          "streak_30",
+		Title:       "یک ماه بی‌وقفه",
+		Description: "سی روز پشت سر هم داستان نوشته",
+		Event:       BadgeEventStoryCreated,
+		Earned:      hasWritingStreak(30),
+	},
+	{
+		ID:          "likes_100",
+		Title:       "صدتا پسند",
+		Description: "داستان‌هاش روی هم صد بار پسندیده شدن",
+		Event:       BadgeEventLikeReceived,
+		Earned:      hasReceivedLikes(100),
+	},
+	{
+		ID:          "likes_1000",
+		Title:       "هزارتا پسند",
+		Description: "داستان‌هاش روی هم هزار بار پسندیده شدن",
+		Event:       BadgeEventLikeReceived,
+		Earned:      hasReceivedLikes(1000),
+	},
+	{
+		ID:          "followers_100",
+		Title:       "صدتا دنبال‌کننده",
+		Description: "صد نفر دنبالش می‌کنن",
+		Event:       BadgeEventFollowReceived,
+		Earned: func(userId uint) (bool, error) {
+			return countAtLeast(database.DB.Model(&models.Follow{}).Where("following_id = ?", userId), 100)
+		},
+	},
+	{
+		ID:          "contest_winner",
+		Title:       "برنده مسابقه",
+		Description: "یکی از برنده‌های مسابقه‌های داستان‌نویسی فنجون",
+	},
+}
+
+func countAtLeast(query *gorm.DB, min int64) (bool, error) {
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count >= min, nil
+}
+
+func hasWritingStreak(days int) func(userId uint) (bool, error) {
+	return func(userId uint) (bool, error) {
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+		writingDays, err := GetWritingDays(userId, today.AddDate(0, 0, -days))
+		if err != nil {
+			return false, err
+		}
+
+		current, _ := GetWritingStreaks(writingDays, today)
+		return current >= days, nil
+	}
+}
+
+// hasReceivedLikes counts likes on the user's published stories, leaving out their own.
+func hasReceivedLikes(min int64) func(userId uint) (bool, error) {
+	return func(userId uint) (bool, error) {
+		userStories := database.DB.Model(&models.Story{}).Where("user_id = ? AND status = ?", userId, "published").Select("id")
+		return countAtLeast(database.DB.Model(&models.Like{}).Where("story_id IN (?) AND user_id <> ?", userStories, userId), min)
+	}
+}
+
+func GetBadge(id string) (Badge, bool) {
+	for _, badge := range Badges {
+		if badge.ID == id {
+			return badge, true
+		}
+	}
+
+	return Badge{}, false
+}
+
+func GetUserBadges(userId uint) ([]AwardedBadge, error) {
+	var userBadges []models.UserBadge
+	if err := database.DB.Where("user_id = ?", userId).Order("created_at ASC").Find(&userBadges).Error; err != nil {
+		return nil, err
+	}
+
+	awarded := []AwardedBadge{}
+	for _, userBadge := range userBadges {
+		if badge, ok := GetBadge(userBadge.Badge); ok {
+			awarded = append(awarded, AwardedBadge{Badge: badge, AwardedAt: userBadge.CreatedAt})
+		}
+	}
+
+	return awarded, nil
+}
+
+// EvaluateBadges checks the rules tied to an event and awards the user any badge they have newly earned.
+func EvaluateBadges(event string, userId uint) {
+	var owned []string
+	if err := database.DB.Model(&models.UserBadge{}).Where("user_id = ?", userId).Pluck("badge", &owned).Error; err != nil {
+		fmt.Printf("Failed to load badges: %v\n", err)
+		return
+	}
+
+	isOwned := make(map[string]bool, len(owned))
+	for _, badge := range owned {
+		isOwned[badge] = true
+	}
+
+	for _, badge := range Badges {
+		if badge.Event != event || badge.Earned == nil || isOwned[badge.ID] {
+			continue
+		}
+
+		earned, err := badge.Earned(userId)
+		if err != nil {
+			fmt.Printf("Failed to evaluate badge %s: %v\n", badge.ID, err)
+			continue
+		}
+
+		if earned {
+			AwardBadge(userId, badge.ID)
+		}
+	}
+}
+
+// AwardBadge gives the user a badge once and lets them know; awarding a badge they already have does nothing.
+func AwardBadge(userId uint, badgeId string) {
+	badge, ok := GetBadge(badgeId)
+	if !ok {
+		return
+	}
+
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.UserBadge{UserID: userId, Badge: badge.ID})
+	if result.Error != nil {
+		fmt.Printf("Failed to award badge %s: %v\n", badge.ID, result.Error)
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	NotifyUser(models.Notification{
+		UserID:  userId,
+		Type:    NotificationBadgeAwarded,
+		Title:   "نشان تازه گرفتی!",
+		Message: fmt.Sprintf("نشان «%s» بهت داده شد", badge.Title),
+		Url:     "/profile",
+	})
+}
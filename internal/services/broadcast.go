@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"gorm.io/gorm"
+)
+
+// Broadcast segments
+const (
+	BroadcastSegmentAll     = "all"
+	BroadcastSegmentPremium = "premium"
+	BroadcastSegmentActive  = "active"
+	BroadcastSegmentContest = "contest"
+	BroadcastSegmentUsers   = "users"
+)
+
+// Recipients are queued at this rate unless the broadcast asks otherwise, each one is a push through the proxy
+const (
+	DefaultBroadcastRatePerMinute = 1000
+	MaxBroadcastRatePerMinute     = 10000
+)
+
+var ErrBroadcastCancelled = errors.New("broadcast was cancelled")
+
+// Delivery counters of a broadcast outlive its delivered jobs, which are cleaned up after a week
+const broadcastReportTTL = 90 * 24 * time.Hour
+
+func broadcastReportKey(broadcastId uint) string {
+	return fmt.Sprintf("broadcast-report:%d", broadcastId)
+}
+
+// BroadcastRecipients selects the IDs of the users in the broadcast's segment.
+func BroadcastRecipients(tx *gorm.DB, broadcast models.Broadcast) *gorm.DB {
+	query := tx.Model(&models.User{}).Where("is_bot = ?", false)
+
+	switch broadcast.Segment {
+	case BroadcastSegmentPremium:
+		query = query.Where("is_premium = ?", true)
+	case BroadcastSegmentActive:
+		// Opening the app refreshes the device's push token, writing anything counts too
+		since := time.Now().AddDate(0, 0, -broadcast.ActiveDays)
+		query = query.Where(
+			"id IN (?) OR id IN (?) OR id IN (?) OR id IN (?) OR id IN (?)",
+			tx.Model(&models.PushToken{}).Where("last_seen_at >= ?", since).Select("user_id"),
+			tx.Model(&models.WebPushSubscription{}).Where("last_seen_at >= ?", since).Select("user_id"),
+			tx.Model(&models.Story{}).Where("created_at >= ?", since).Select("user_id"),
+			tx.Model(&models.Like{}).Where("created_at >= ?", since).Select("user_id"),
+			tx.Model(&models.Comment{}).Where("created_at >= ?", since).Select("user_id"),
+		)
+	case BroadcastSegmentContest:
+		query = query.Where("id IN (?)", tx.Model(&models.ContestEntry{}).Where("contest_id = ?", broadcast.ContestID).Select("user_id"))
+	case BroadcastSegmentUsers:
+		query = query.Where("id IN ?", broadcast.UserIDs)
+	}
+
+	return query
+}
+
+func broadcastChannels(broadcast models.Broadcast) []string {
+	var channels []string
+	if broadcast.InApp {
+		channels = append(channels, "in_app")
+	}
+
+	if broadcast.Push {
+		channels = append(channels, "push", "web_push")
+	}
+
+	return channels
+}
+
+// QueueBroadcastBatch queues the broadcast for its next recipients, at most limit of them, and marks it completed
+// once everyone in the segment is queued.
+func QueueBroadcastBatch(broadcast models.Broadcast, limit int) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var userIds []uint
+		if err := BroadcastRecipients(tx, broadcast).
+			Where("id > ?", broadcast.LastUserID).
+			Order("id ASC").
+			Limit(limit).
+			Pluck("id", &userIds).Error; err != nil {
+			return err
+		}
+
+		channels := broadcastChannels(broadcast)
+		jobs := make([]models.NotificationJob, 0, len(userIds)*len(channels))
+
+		for _, userId := range userIds {
+			for _, job := range newNotificationJobs(models.Notification{
+				UserID:  userId,
+				Type:    NotificationAnnouncement,
+				Title:   broadcast.Title,
+				Message: broadcast.Message,
+				Url:     broadcast.Url,
+			}, channels) {
+				job.BroadcastID = &broadcast.ID
+				jobs = append(jobs, job)
+			}
+		}
+
+		if len(jobs) > 0 {
+			if err := tx.CreateInBatches(&jobs, 500).Error; err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		updates := map[string]any{
+			"status":           "sending",
+			"recipients_count": gorm.Expr("recipients_count + ?", len(userIds)),
+		}
+
+		if broadcast.StartedAt == nil {
+			updates["started_at"] = now
+		}
+
+		if len(userIds) > 0 {
+			updates["last_user_id"] = userIds[len(userIds)-1]
+		}
+
+		if len(userIds) < limit {
+			updates["status"] = "completed"
+			updates["completed_at"] = now
+		}
+
+		result := tx.Model(&models.Broadcast{}).Where("id = ? AND status IN ?", broadcast.ID, []string{"scheduled", "sending"}).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		// Cancelled meanwhile, nothing of this batch is queued
+		if result.RowsAffected == 0 {
+			return ErrBroadcastCancelled
+		}
+
+		return nil
+	})
+}
+
+// RecordBroadcastOutcome counts a job outcome of the broadcast, alongside the overall notification metrics.
+func RecordBroadcastOutcome(broadcastId uint, channel string, outcome string) {
+	ctx := context.Background()
+	key := broadcastReportKey(broadcastId)
+
+	if err := database.RedisClient.HIncrBy(ctx, key, channel+":"+outcome, 1).Err(); err != nil {
+		fmt.Printf("Failed to record broadcast report: %v\n", err)
+		return
+	}
+
+	database.RedisClient.Expire(ctx, key, broadcastReportTTL)
+}
+
+func GetBroadcastReport(broadcastId uint) (*models.BroadcastReport, error) {
+	counters, err := database.RedisClient.HGetAll(context.Background(), broadcastReportKey(broadcastId)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.BroadcastReport{Outcomes: make(map[string]int64, len(counters))}
+	for field, value := range counters {
+		report.Outcomes[field], _ = strconv.ParseInt(value, 10, 64)
+	}
+
+	if err := database.DB.Model(&models.NotificationJob{}).
+		Where("broadcast_id = ? AND status IN ?", broadcastId, []string{"pending", "processing"}).
+		Count(&report.Pending).Error; err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
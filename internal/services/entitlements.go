@@ -0,0 +1,53 @@
+package services
+
+import "github.com/freakingeek/fenjoon/internal/models"
+
+// Unlimited is used as a quota value for limits that don't apply to a plan.
+const Unlimited = -1
+
+type Entitlements struct {
+	Plan                 string `json:"plan"`
+	MaxPrivateStories    int    `json:"maxPrivateStories"`
+	MaxStoryLength       int    `json:"maxStoryLength"`
+	MaxBookmarkFolders   int    `json:"maxBookmarkFolders"`
+	MaxDrafts            int    `json:"maxDrafts"`
+	CanViewAnalytics     bool   `json:"canViewAnalytics"`
+	AnalyticsHistoryDays int    `json:"analyticsHistoryDays"`
+}
+
+var planEntitlements = map[string]Entitlements{
+	"free": {
+		Plan:                 "free",
+		MaxPrivateStories:    3,
+		MaxStoryLength:       250,
+		MaxBookmarkFolders:   2,
+		MaxDrafts:            10,
+		CanViewAnalytics:     true,
+		AnalyticsHistoryDays: 7,
+	},
+	"premium": {
+		Plan:                 "premium",
+		MaxPrivateStories:    Unlimited,
+		MaxStoryLength:       500,
+		MaxBookmarkFolders:   Unlimited,
+		MaxDrafts:            100,
+		CanViewAnalytics:     true,
+		AnalyticsHistoryDays: 365,
+	},
+}
+
+func GetEntitlements(user models.User) Entitlements {
+	if user.IsPremium {
+		return planEntitlements["premium"]
+	}
+
+	return planEntitlements["free"]
+}
+
+func WithinQuota(quota int, used int64) bool {
+	return quota == Unlimited || used < int64(quota)
+}
+
+func GetPlanEntitlements(plan string) Entitlements {
+	return planEntitlements[plan]
+}
@@ -3,41 +3,65 @@ package services
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/models"
+)
+
+// expoPushProxy forwards to Expo's push API, /send and /getReceipts
+const expoPushProxy = "https://proxy.fnjo.ir/push"
+
+// Expo accepts at most this many messages per send and ticket IDs per receipts request
+const (
+	expoSendBatchSize     = 100
+	expoReceiptsBatchSize = 1000
 )
 
+// ExpoDeviceNotRegistered is the error Expo reports for tokens of uninstalled apps. Those tokens are removed.
+const ExpoDeviceNotRegistered = "DeviceNotRegistered"
+
+var expoClient = &http.Client{Timeout: 15 * time.Second}
+
 type PushMessage struct {
-	To        []string `json:"to"`
-	Title     string   `json:"title"`
-	Body      string   `json:"body"`
-	Sound     string   `json:"sound,omitempty"`
-	Priority  string   `json:"priority,omitempty"`
-	ChannelId string   `json:"channelId,omitempty"`
+	To        string         `json:"to"`
+	Title     string         `json:"title"`
+	Body      string         `json:"body"`
+	Data      map[string]any `json:"data,omitempty"` // Read by the app, e.g. the "url" to open
+	Sound     string         `json:"sound,omitempty"`
+	Priority  string         `json:"priority,omitempty"`
+	ChannelId string         `json:"channelId,omitempty"`
 }
 
-func SendPushNotification(tokens []string, text string) error {
-	if len(tokens) == 0 {
-		return errors.New("push token list is empty")
-	}
+// PushTicket is Expo's answer to one message, a receipt ID when it was accepted.
+type PushTicket struct {
+	Status  string `json:"status"` // "ok" or "error"
+	ID      string `json:"id"`
+	Message string `json:"message"`
+	Details struct {
+		Error string `json:"error"`
+	} `json:"details"`
+}
 
-	message := PushMessage{
-		To:        tokens,
-		Title:     "فنجون",
-		Body:      text,
-		Sound:     "default",
-		Priority:  "high",
-		ChannelId: "default",
-	}
+// PushReceipt tells whether an accepted message reached the device's push service.
+type PushReceipt struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Details struct {
+		Error string `json:"error"`
+	} `json:"details"`
+}
 
-	jsonData, err := json.Marshal(message)
+func postToExpo(path string, body any, result any) error {
+	jsonData, err := json.Marshal(body)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", "https://proxy.fnjo.ir/push/send", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest("POST", expoPushProxy+path, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return err
 	}
@@ -45,20 +69,141 @@ func SendPushNotification(tokens []string, text string) error {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := expoClient.Do(req)
 	if err != nil {
 		return err
 	}
 
 	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
+	respBody, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to send push notification: %s", string(body))
+		return fmt.Errorf("expo %s failed: %s", path, string(respBody))
+	}
+
+	return json.Unmarshal(respBody, result)
+}
+
+// SendPushMessages sends the messages in batches Expo accepts and returns their tickets, in the same order.
+func SendPushMessages(messages []PushMessage) ([]PushTicket, error) {
+	tickets := make([]PushTicket, 0, len(messages))
+
+	for start := 0; start < len(messages); start += expoSendBatchSize {
+		end := min(start+expoSendBatchSize, len(messages))
+
+		var response struct {
+			Data []PushTicket `json:"data"`
+		}
+
+		if err := postToExpo("/send", messages[start:end], &response); err != nil {
+			return tickets, err
+		}
+
+		tickets = append(tickets, response.Data...)
+	}
+
+	return tickets, nil
+}
+
+// GetPushReceipts fetches the receipts of the given tickets. Receipts Expo doesn't have yet are missing from the result.
+func GetPushReceipts(ticketIds []string) (map[string]PushReceipt, error) {
+	receipts := make(map[string]PushReceipt, len(ticketIds))
+
+	for start := 0; start < len(ticketIds); start += expoReceiptsBatchSize {
+		end := min(start+expoReceiptsBatchSize, len(ticketIds))
+
+		var response struct {
+			Data map[string]PushReceipt `json:"data"`
+		}
+
+		if err := postToExpo("/getReceipts", map[string]any{"ids": ticketIds[start:end]}, &response); err != nil {
+			return receipts, err
+		}
+
+		for id, receipt := range response.Data {
+			receipts[id] = receipt
+		}
+	}
+
+	return receipts, nil
+}
+
+// SendPushNotification pushes the notification to every device of its user, with a deep link to open in the data.
+// Tickets are kept to check their receipts later, and tokens Expo no longer knows are removed.
+func SendPushNotification(notification models.Notification) error {
+	var pushTokens []models.PushToken
+	if err := database.DB.Where("user_id = ?", notification.UserID).Find(&pushTokens).Error; err != nil {
+		return err
+	}
+
+	if len(pushTokens) == 0 {
+		return nil
+	}
+
+	title := notification.Title
+	if title == "" {
+		title = "فنجون"
+	}
+
+	data := map[string]any{"url": notification.Url, "type": notification.Type}
+	if notification.TargetID != nil {
+		data["targetType"] = notification.TargetType
+		data["targetId"] = *notification.TargetID
 	}
 
-	fmt.Println("Push sent successfully:", string(body))
-	return nil
+	messages := make([]PushMessage, len(pushTokens))
+	for i, pushToken := range pushTokens {
+		messages[i] = PushMessage{
+			To:        pushToken.Token,
+			Title:     title,
+			Body:      notification.Message,
+			Data:      data,
+			Sound:     "default",
+			Priority:  "high",
+			ChannelId: "default",
+		}
+	}
+
+	tickets, err := SendPushMessages(messages)
+	handlePushTickets(pushTokens, tickets)
+
+	return err
+}
+
+func handlePushTickets(pushTokens []models.PushToken, tickets []PushTicket) {
+	var accepted []models.PushTicket
+
+	for i, ticket := range tickets {
+		if i >= len(pushTokens) {
+			break
+		}
+
+		if ticket.Status == "ok" {
+			accepted = append(accepted, models.PushTicket{TicketID: ticket.ID, PushTokenID: pushTokens[i].ID})
+			continue
+		}
+
+		if ticket.Details.Error == ExpoDeviceNotRegistered {
+			RemovePushToken(pushTokens[i].ID)
+			continue
+		}
+
+		fmt.Printf("Push to token %d failed: %s\n", pushTokens[i].ID, ticket.Message)
+	}
+
+	if len(accepted) == 0 {
+		return
+	}
+
+	if err := database.DB.Create(&accepted).Error; err != nil {
+		fmt.Printf("Failed to save push tickets: %v\n", err)
+	}
+}
+
+// RemovePushToken forgets a device Expo reported as no longer registered.
+func RemovePushToken(pushTokenId uint) {
+	if err := database.DB.Delete(&models.PushToken{}, pushTokenId).Error; err != nil {
+		fmt.Printf("Failed to remove push token %d: %v\n", pushTokenId, err)
+	}
 }
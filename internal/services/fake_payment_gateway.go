@@ -0,0 +1,43 @@
+package services
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// FakePaymentGateway approves every payment without leaving the app, for local development and staging.
+type FakePaymentGateway struct{}
+
+func NewFakePaymentGateway() *FakePaymentGateway {
+	return &FakePaymentGateway{}
+}
+
+func (g *FakePaymentGateway) Name() string {
+	return "fake"
+}
+
+func (g *FakePaymentGateway) RequestPayment(amount int64, description string, callbackUrl string) (string, string, error) {
+	authority := "FAKE-" + uuid.New().String()
+
+	redirectUrl, err := url.Parse(callbackUrl)
+	if err != nil {
+		return "", "", err
+	}
+
+	query := redirectUrl.Query()
+	query.Set("Authority", authority)
+	query.Set("Status", "OK")
+	redirectUrl.RawQuery = query.Encode()
+
+	return authority, redirectUrl.String(), nil
+}
+
+func (g *FakePaymentGateway) VerifyPayment(authority string, amount int64) (string, error) {
+	if !strings.HasPrefix(authority, "FAKE-") {
+		return "", ErrPaymentNotVerified
+	}
+
+	return strings.TrimPrefix(authority, "FAKE-")[:8], nil
+}
@@ -1,12 +1,384 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
 	"github.com/freakingeek/fenjoon/internal/database"
 	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Notification types
+const (
+	NotificationStoryLiked          = "story_liked"
+	NotificationStoryCommented      = "story_commented"
+	NotificationCommentLiked        = "comment_liked"
+	NotificationNewFollower         = "new_follower"
+	NotificationMentioned           = "mentioned"
+	NotificationNewStory            = "new_story"
+	NotificationStoryContinued      = "story_continued"
+	NotificationStoryPublished      = "story_published"
+	NotificationCollectionUpdated   = "collection_updated"
+	NotificationContestResult       = "contest_result"
+	NotificationBadgeAwarded        = "badge_awarded"
+	NotificationReferralRewarded    = "referral_rewarded"
+	NotificationGiftReceived        = "gift_received"
+	NotificationSubscriptionExpiry  = "subscription_expiry"
+	NotificationVerificationUpdated = "verification_updated"
+	NotificationQuietHoursDigest    = "quiet_hours_digest"
+	NotificationWeeklyDigest        = "weekly_digest"
+	NotificationAnnouncement        = "announcement"
 )
 
+// Notifications of the same type on the same target are grouped into one row within this window
+const notificationGroupWindow = 6 * time.Hour
+
+// At most one push goes out per target within this window, however many actors are grouped meanwhile
+const notificationPushThrottle = 15 * time.Minute
+
+const notificationLatestActors = 3
+
+// groupedNotificationMessages word the message of grouped notifications, from the latest actor's name and the number of others.
+// Only these types are grouped.
+var groupedNotificationMessages = map[string]string{
+	NotificationStoryLiked:     "%s و %s نفر دیگه از داستانت خوششون اومد",
+	NotificationStoryCommented: "%s و %s نفر دیگه روی داستانت نقد نوشتن",
+	NotificationCommentLiked:   "%s و %s نفر دیگه از نقدت خوششون اومد",
+	NotificationNewFollower:    "%s و %s نفر دیگه از حالا دنبالت میکنن!",
+}
+
+type NotificationType struct {
+	ID    string `json:"type"`
+	Title string `json:"title"`
+}
+
+// NotificationTypes are the types users can turn on or off. Account notifications, like subscription and
+// verification updates, aren't listed and are always delivered.
+var NotificationTypes = []NotificationType{
+	{ID: NotificationStoryLiked, Title: "پسندیدن داستان‌هام"},
+	{ID: NotificationStoryCommented, Title: "نقد روی داستان‌هام"},
+	{ID: NotificationCommentLiked, Title: "پسندیدن نقدهام"},
+	{ID: NotificationNewFollower, Title: "دنبال کننده‌های جدید"},
+	{ID: NotificationMentioned, Title: "اشاره به من"},
+	{ID: NotificationNewStory, Title: "داستان‌های تازه نویسنده‌هایی که دنبال می‌کنم"},
+	{ID: NotificationStoryContinued, Title: "ادامه دادن داستان‌هام"},
+	{ID: NotificationStoryPublished, Title: "انتشار داستان‌های زمان‌بندی‌شده"},
+	{ID: NotificationCollectionUpdated, Title: "قسمت‌های تازه مجموعه‌هایی که دنبال می‌کنم"},
+	{ID: NotificationContestResult, Title: "نتایج مسابقه‌ها"},
+	{ID: NotificationBadgeAwarded, Title: "نشان‌های تازه"},
+	{ID: NotificationWeeklyDigest, Title: "خلاصه هفتگی"},
+}
+
+func IsConfigurableNotificationType(notificationType string) bool {
+	for _, t := range NotificationTypes {
+		if t.ID == notificationType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetNotificationSettings lists the user's channels for every configurable type, filling in the defaults.
+func GetNotificationSettings(userId uint) ([]models.NotificationSetting, error) {
+	var saved []models.NotificationSetting
+	if err := database.DB.Where("user_id = ?", userId).Find(&saved).Error; err != nil {
+		return nil, err
+	}
+
+	savedByType := make(map[string]models.NotificationSetting, len(saved))
+	for _, setting := range saved {
+		savedByType[setting.Type] = setting
+	}
+
+	settings := make([]models.NotificationSetting, len(NotificationTypes))
+	for i, t := range NotificationTypes {
+		setting, ok := savedByType[t.ID]
+		if !ok {
+			setting = models.NotificationSetting{UserID: userId, Type: t.ID, InApp: true, Push: true}
+		}
+
+		setting.Title = t.Title
+		settings[i] = setting
+	}
+
+	return settings, nil
+}
+
+// getNotificationSetting finds the channels a notification should go out on.
+func getNotificationSetting(userId uint, notificationType string) (models.NotificationSetting, error) {
+	setting := models.NotificationSetting{UserID: userId, Type: notificationType, InApp: true, Push: true}
+	if !IsConfigurableNotificationType(notificationType) {
+		return setting, nil
+	}
+
+	err := database.DB.Where("user_id = ? AND type = ?", userId, notificationType).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return setting, nil
+	}
+
+	return setting, err
+}
+
+func isGroupedNotification(notification models.Notification) bool {
+	_, ok := groupedNotificationMessages[notification.Type]
+	return ok && notification.ActorID != nil
+}
+
+// notificationTargetCondition matches notifications on the same target, including those without one, like new followers.
+func notificationTargetCondition(db *gorm.DB, notification models.Notification) *gorm.DB {
+	if notification.TargetID == nil {
+		return db.Where("target_type = ? AND target_id IS NULL", notification.TargetType)
+	}
+
+	return db.Where("target_type = ? AND target_id = ?", notification.TargetType, *notification.TargetID)
+}
+
+// groupNotification folds the notification into a recent one of the same type on the same target and returns its ID.
+// It returns 0 when there is no such notification to group into.
+func groupNotification(notification models.Notification) (uint, error) {
+	var groupId uint
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND type = ? AND COALESCE(last_actor_at, created_at) > ?", notification.UserID, notification.Type, time.Now().Add(-notificationGroupWindow))
+
+		var group models.Notification
+		err := notificationTargetCondition(query, notification).Order("COALESCE(last_actor_at, created_at) DESC").First(&group).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		groupId = group.ID
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.NotificationActor{NotificationID: group.ID, UserID: *notification.ActorID})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		// The same actor again, like someone liking a story a second time after disliking it
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		var actor models.User
+		if err := tx.First(&actor, *notification.ActorID).Error; err != nil {
+			return err
+		}
+
+		actorsCount := group.ActorsCount + 1
+		others := utils.ToPersianDigits(strconv.Itoa(int(actorsCount) - 1))
+
+		return tx.Model(&group).Updates(map[string]any{
+			"actor_id":      actor.ID,
+			"actors_count":  actorsCount,
+			"message":       fmt.Sprintf(groupedNotificationMessages[notification.Type], utils.GetUserDisplayName(actor), others),
+			"is_read":       false,
+			"last_actor_at": time.Now(),
+		}).Error
+	})
+
+	return groupId, err
+}
+
 func SendInAppNotification(notification models.Notification) error {
-	if err := database.DB.Create(&notification).Error; err != nil {
+	notificationId, err := storeInAppNotification(notification)
+	if err != nil {
+		return err
+	}
+
+	publishNotification(notification.UserID, notificationId)
+
+	return nil
+}
+
+// storeInAppNotification saves the notification, or groups it into an earlier one, and returns the ID of the row clients should show.
+func storeInAppNotification(notification models.Notification) (uint, error) {
+	if isGroupedNotification(notification) {
+		groupId, err := groupNotification(notification)
+		if err != nil || groupId != 0 {
+			return groupId, err
+		}
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if notification.ActorID != nil {
+			notification.ActorsCount = 1
+		}
+
+		if err := tx.Create(&notification).Error; err != nil {
+			return err
+		}
+
+		if notification.ActorID == nil {
+			return nil
+		}
+
+		return tx.Create(&models.NotificationActor{NotificationID: notification.ID, UserID: *notification.ActorID}).Error
+	})
+
+	return notification.ID, err
+}
+
+// publishNotification sends the new or updated notification, and the unread count it changed, to the user's streams.
+func publishNotification(userId uint, notificationId uint) {
+	var notification models.Notification
+	if err := database.DB.Preload("Actor").First(&notification, notificationId).Error; err != nil {
+		fmt.Printf("Failed to find notification to publish: %v\n", err)
+		return
+	}
+
+	notifications := []models.Notification{notification}
+	if err := LoadLatestActors(notifications); err != nil {
+		fmt.Printf("Failed to find notification actors: %v\n", err)
+		return
+	}
+
+	PublishNotificationEvent(userId, NotificationEventNotification, notifications[0])
+	PublishUnreadCount(userId)
+}
+
+func pushThrottleKey(channel string, notification models.Notification) string {
+	targetId := "none"
+	if notification.TargetID != nil {
+		targetId = strconv.Itoa(int(*notification.TargetID))
+	}
+
+	return fmt.Sprintf("notification-%s:%d:%s:%s:%s", channel, notification.UserID, notification.Type, notification.TargetType, targetId)
+}
+
+func releasePushThrottle(channel string, notification models.Notification) {
+	if !isGroupedNotification(notification) {
+		return
+	}
+
+	if err := database.RedisClient.Del(context.Background(), pushThrottleKey(channel, notification)).Err(); err != nil {
+		fmt.Printf("Failed to release push throttle: %v\n", err)
+	}
+}
+
+// isPushThrottled reports whether a push already went out recently on the channel for the notification's group.
+func isPushThrottled(channel string, notification models.Notification) bool {
+	if !isGroupedNotification(notification) {
+		return false
+	}
+
+	sent, err := database.RedisClient.SetNX(context.Background(), pushThrottleKey(channel, notification), 1, notificationPushThrottle).Result()
+	if err != nil {
+		fmt.Printf("Failed to check push throttle: %v\n", err)
+		return false
+	}
+
+	return !sent
+}
+
+// LoadLatestActors fills in the most recent actors of grouped notifications.
+func LoadLatestActors(notifications []models.Notification) error {
+	for i := range notifications {
+		if notifications[i].ActorsCount < 2 {
+			if notifications[i].Actor != nil {
+				notifications[i].LatestActors = []models.User{*notifications[i].Actor}
+			}
+
+			continue
+		}
+
+		if err := database.DB.
+			Joins("JOIN notification_actors ON notification_actors.user_id = users.id").
+			Where("notification_actors.notification_id = ?", notifications[i].ID).
+			Order("notification_actors.created_at DESC").
+			Limit(notificationLatestActors).
+			Find(&notifications[i].LatestActors).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NotifyUser queues the notification for delivery on every channel. Use EnqueueNotification to queue it as part of a transaction.
+func NotifyUser(notification models.Notification) {
+	if err := EnqueueNotification(database.DB, notification); err != nil {
+		fmt.Printf("Failed to queue notification: %v\n", err)
+	}
+}
+
+// deliverInAppNotification records the notification in-app, if the user kept in-app notifications of its type on.
+func deliverInAppNotification(notification models.Notification) error {
+	setting, err := getNotificationSetting(notification.UserID, notification.Type)
+	if err != nil {
+		return err
+	}
+
+	if !setting.InApp {
+		return nil
+	}
+
+	return SendInAppNotification(notification)
+}
+
+// deliverPushNotification pushes the notification to the user's devices, if the user kept push notifications
+// of its type on. Likes, comments and follows on the same target are only pushed once in a while, and pushes during
+// the user's quiet hours wait for the digest sent when they end.
+func deliverPushNotification(notification models.Notification) error {
+	setting, err := getNotificationSetting(notification.UserID, notification.Type)
+	if err != nil {
+		return err
+	}
+
+	if !setting.Push || isPushThrottled("push", notification) {
+		return nil
+	}
+
+	if deferred, err := deferPushForQuietHours("push", notification); deferred || err != nil {
+		return err
+	}
+
+	if err := SendPushNotification(notification); err != nil {
+		// Let the retry through the throttle
+		releasePushThrottle("push", notification)
+		return err
+	}
+
+	return nil
+}
+
+// deliverWebPushNotification pushes the notification to the user's browsers. It follows the same push settings and throttling as the app.
+func deliverWebPushNotification(notification models.Notification) error {
+	setting, err := getNotificationSetting(notification.UserID, notification.Type)
+	if err != nil {
+		return err
+	}
+
+	if !setting.Push {
+		return nil
+	}
+
+	// Nothing to retry until VAPID keys are configured
+	if _, err := GetVapidPublicKey(); err != nil {
+		return nil
+	}
+
+	if isPushThrottled("web_push", notification) {
+		return nil
+	}
+
+	if deferred, err := deferPushForQuietHours("web_push", notification); deferred || err != nil {
+		return err
+	}
+
+	if err := SendWebPushNotification(notification); err != nil {
+		releasePushThrottle("web_push", notification)
 		return err
 	}
 
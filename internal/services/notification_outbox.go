@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"gorm.io/gorm"
+)
+
+// NotificationChannels deliver queued notifications. A channel returning an error has its job retried.
+var NotificationChannels = map[string]func(notification models.Notification) error{
+	"in_app":   deliverInAppNotification,
+	"push":     deliverPushNotification,
+	"web_push": deliverWebPushNotification,
+}
+
+// Failed jobs are retried with exponential backoff and dead-lettered after the last attempt
+const (
+	NotificationJobMaxAttempts = 8
+	notificationRetryBaseDelay = 30 * time.Second
+	notificationRetryMaxDelay  = 2 * time.Hour
+)
+
+// NotificationMetricsKey is a Redis hash of "<channel>:<outcome>" counters, shared by every process delivering jobs.
+const NotificationMetricsKey = "notification-metrics"
+
+// EnqueueNotification queues the notification on every channel. Pass the transaction of the action causing it,
+// so the notification is only sent if the action is committed.
+func EnqueueNotification(tx *gorm.DB, notification models.Notification) error {
+	channels := make([]string, 0, len(NotificationChannels))
+	for channel := range NotificationChannels {
+		channels = append(channels, channel)
+	}
+
+	return EnqueueNotificationOn(tx, notification, channels...)
+}
+
+// EnqueueNotificationOn queues the notification on the given channels only.
+func EnqueueNotificationOn(tx *gorm.DB, notification models.Notification, channels ...string) error {
+	jobs := newNotificationJobs(notification, channels)
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	return tx.Create(&jobs).Error
+}
+
+func newNotificationJobs(notification models.Notification, channels []string) []models.NotificationJob {
+	now := time.Now()
+
+	jobs := make([]models.NotificationJob, 0, len(channels))
+	for _, channel := range channels {
+		jobs = append(jobs, models.NotificationJob{
+			Channel:       channel,
+			Status:        "pending",
+			NextAttemptAt: now,
+			UserID:        notification.UserID,
+			Type:          notification.Type,
+			ActorID:       notification.ActorID,
+			TargetType:    notification.TargetType,
+			TargetID:      notification.TargetID,
+			Title:         notification.Title,
+			Message:       notification.Message,
+			Image:         notification.Image,
+			Url:           notification.Url,
+		})
+	}
+
+	return jobs
+}
+
+// DeliverNotificationJob sends the job's notification on its channel.
+func DeliverNotificationJob(job models.NotificationJob) error {
+	deliver, ok := NotificationChannels[job.Channel]
+	if !ok {
+		return fmt.Errorf("unknown notification channel %q", job.Channel)
+	}
+
+	return deliver(models.Notification{
+		UserID:     job.UserID,
+		Type:       job.Type,
+		ActorID:    job.ActorID,
+		TargetType: job.TargetType,
+		TargetID:   job.TargetID,
+		Title:      job.Title,
+		Message:    job.Message,
+		Image:      job.Image,
+		Url:        job.Url,
+	})
+}
+
+// NotificationRetryDelay is how long to wait before the next attempt, after the given number of failed ones.
+func NotificationRetryDelay(attempts int) time.Duration {
+	delay := time.Duration(float64(notificationRetryBaseDelay) * math.Pow(2, float64(attempts-1)))
+	if delay <= 0 || delay > notificationRetryMaxDelay {
+		return notificationRetryMaxDelay
+	}
+
+	return delay
+}
+
+// RecordNotificationOutcome counts a job outcome ("delivered", "failed" or "dead") for its channel.
+func RecordNotificationOutcome(channel string, outcome string) {
+	if err := database.RedisClient.HIncrBy(context.Background(), NotificationMetricsKey, channel+":"+outcome, 1).Err(); err != nil {
+		fmt.Printf("Failed to record notification metrics: %v\n", err)
+	}
+}
+
+func GetNotificationMetrics() (map[string]string, error) {
+	return database.RedisClient.HGetAll(context.Background(), NotificationMetricsKey).Result()
+}
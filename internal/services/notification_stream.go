@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// NotificationEventsChannel is the Redis pub/sub channel every API instance listens on to fan events out to its own streams.
+const NotificationEventsChannel = "notification-events"
+
+// Recent events are kept per user so a reconnecting client can resume from its last event ID
+const (
+	notificationEventsKept = 100
+	notificationEventsTTL  = 24 * time.Hour
+)
+
+// Event names sent to streams
+const (
+	NotificationEventNotification = "notification"
+	NotificationEventUnreadCount  = "unread-count"
+)
+
+type NotificationEvent struct {
+	ID     string          `json:"id"` // The Redis stream entry ID, "<ms>-<seq>"
+	UserID uint            `json:"userId"`
+	Event  string          `json:"event"`
+	Data   json.RawMessage `json:"data"`
+}
+
+var notificationStreams = struct {
+	sync.Mutex
+	listening   bool
+	subscribers map[uint]map[chan NotificationEvent]struct{}
+}{subscribers: map[uint]map[chan NotificationEvent]struct{}{}}
+
+func notificationEventsKey(userId uint) string {
+	return fmt.Sprintf("notification-stream:%d", userId)
+}
+
+// PublishNotificationEvent records the event for resuming and fans it out to every instance with a stream open for the user.
+func PublishNotificationEvent(userId uint, event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		fmt.Printf("Failed to encode notification event: %v\n", err)
+		return
+	}
+
+	ctx := context.Background()
+	key := notificationEventsKey(userId)
+
+	id, err := database.RedisClient.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: notificationEventsKept,
+		Approx: true,
+		Values: map[string]any{"event": event, "data": payload},
+	}).Result()
+	if err != nil {
+		fmt.Printf("Failed to record notification event: %v\n", err)
+		return
+	}
+
+	database.RedisClient.Expire(ctx, key, notificationEventsTTL)
+
+	message, err := json.Marshal(NotificationEvent{ID: id, UserID: userId, Event: event, Data: payload})
+	if err != nil {
+		fmt.Printf("Failed to encode notification event: %v\n", err)
+		return
+	}
+
+	if err := database.RedisClient.Publish(ctx, NotificationEventsChannel, message).Err(); err != nil {
+		fmt.Printf("Failed to publish notification event: %v\n", err)
+	}
+}
+
+// PublishUnreadCount sends the user's current unread notifications count to their streams.
+func PublishUnreadCount(userId uint) {
+	var count int64
+	if err := database.DB.Model(&models.Notification{}).Where("user_id = ? AND is_read = false", userId).Count(&count).Error; err != nil {
+		fmt.Printf("Failed to count unread notifications: %v\n", err)
+		return
+	}
+
+	PublishNotificationEvent(userId, NotificationEventUnreadCount, map[string]any{"count": count})
+}
+
+// SubscribeNotificationEvents opens a stream of the user's events on this instance. The returned function closes it.
+func SubscribeNotificationEvents(userId uint) (<-chan NotificationEvent, func()) {
+	events := make(chan NotificationEvent, 16)
+
+	notificationStreams.Lock()
+	if !notificationStreams.listening {
+		notificationStreams.listening = true
+		go listenNotificationEvents()
+	}
+
+	if notificationStreams.subscribers[userId] == nil {
+		notificationStreams.subscribers[userId] = map[chan NotificationEvent]struct{}{}
+	}
+	notificationStreams.subscribers[userId][events] = struct{}{}
+	notificationStreams.Unlock()
+
+	unsubscribe := func() {
+		notificationStreams.Lock()
+		delete(notificationStreams.subscribers[userId], events)
+		if len(notificationStreams.subscribers[userId]) == 0 {
+			delete(notificationStreams.subscribers, userId)
+		}
+		notificationStreams.Unlock()
+	}
+
+	return events, unsubscribe
+}
+
+// listenNotificationEvents hands the events published by any instance to the streams open on this one.
+func listenNotificationEvents() {
+	pubsub := database.RedisClient.Subscribe(context.Background(), NotificationEventsChannel)
+	defer pubsub.Close()
+
+	for message := range pubsub.Channel() {
+		var event NotificationEvent
+		if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+			fmt.Printf("Failed to decode notification event: %v\n", err)
+			continue
+		}
+
+		notificationStreams.Lock()
+		for events := range notificationStreams.subscribers[event.UserID] {
+			// A stream that can't keep up misses the event rather than holding up everyone else's
+			select {
+			case events <- event:
+			default:
+			}
+		}
+		notificationStreams.Unlock()
+	}
+}
+
+// GetNotificationEventsSince returns the user's recorded events after lastEventId, oldest first.
+func GetNotificationEventsSince(userId uint, lastEventId string) ([]NotificationEvent, error) {
+	entries, err := database.RedisClient.XRange(context.Background(), notificationEventsKey(userId), "("+lastEventId, "+").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]NotificationEvent, 0, len(entries))
+	for _, entry := range entries {
+		event, _ := entry.Values["event"].(string)
+		data, _ := entry.Values["data"].(string)
+		events = append(events, NotificationEvent{ID: entry.ID, UserID: userId, Event: event, Data: json.RawMessage(data)})
+	}
+
+	return events, nil
+}
+
+// IsNotificationEventAfter compares two event IDs, so events already replayed on resume aren't sent twice.
+func IsNotificationEventAfter(id string, other string) bool {
+	if other == "" {
+		return true
+	}
+
+	idMs, idSeq := parseNotificationEventId(id)
+	otherMs, otherSeq := parseNotificationEventId(other)
+
+	return idMs > otherMs || (idMs == otherMs && idSeq > otherSeq)
+}
+
+func parseNotificationEventId(id string) (uint64, uint64) {
+	ms, seq, _ := strings.Cut(id, "-")
+	msValue, _ := strconv.ParseUint(ms, 10, 64)
+	seqValue, _ := strconv.ParseUint(seq, 10, 64)
+
+	return msValue, seqValue
+}
+
+// IsValidNotificationEventId checks a client supplied Last-Event-ID before it reaches Redis.
+func IsValidNotificationEventId(id string) bool {
+	ms, seq, found := strings.Cut(id, "-")
+	if !found {
+		return false
+	}
+
+	if _, err := strconv.ParseUint(ms, 10, 64); err != nil {
+		return false
+	}
+
+	_, err := strconv.ParseUint(seq, 10, 64)
+	return err == nil
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"errors"
+	"os"
+)
+
+var ErrPaymentNotVerified = errors.New("payment was not verified by the gateway")
+
+type PaymentGateway interface {
+	Name() string
+	// RequestPayment registers a new payment and returns the gateway's reference and the URL the user should be sent to.
+	RequestPayment(amount int64, description string, callbackUrl string) (authority string, redirectUrl string, err error)
+	// VerifyPayment confirms a payment after the user returns to the callback URL and returns the gateway's tracking code.
+	VerifyPayment(authority string, amount int64) (refId string, err error)
+}
+
+// GetPaymentGateway returns the gateway new payments are made with.
+func GetPaymentGateway() PaymentGateway {
+	switch os.Getenv("PAYMENT_GATEWAY") {
+	case "fake":
+		return NewFakePaymentGateway()
+	default:
+		return NewZarinpalGateway(os.Getenv("ZARINPAL_MERCHANT_ID"), os.Getenv("ZARINPAL_SANDBOX") == "true")
+	}
+}
+
+// GetPaymentGatewayByName returns the gateway a payment was made with, so it is verified where it was paid.
+func GetPaymentGatewayByName(name string) (PaymentGateway, bool) {
+	switch name {
+	case "fake":
+		return NewFakePaymentGateway(), true
+	case "zarinpal":
+		return NewZarinpalGateway(os.Getenv("ZARINPAL_MERCHANT_ID"), os.Getenv("ZARINPAL_SANDBOX") == "true"), true
+	default:
+		return nil, false
+	}
+}
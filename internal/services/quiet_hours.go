@@ -0,0 +1,160 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/utils"
+	"gorm.io/gorm"
+)
+
+const DefaultTimezone = "Asia/Tehran"
+
+const quietHoursLayout = "15:04"
+
+// Suggested window for users who haven't set their own
+const (
+	defaultQuietHoursStart = "23:00"
+	defaultQuietHoursEnd   = "08:00"
+)
+
+// UserLocation loads the user's timezone, falling back to the default one for unknown names.
+func UserLocation(timezone string) *time.Location {
+	if timezone != "" {
+		if location, err := time.LoadLocation(timezone); err == nil {
+			return location
+		}
+	}
+
+	location, err := time.LoadLocation(DefaultTimezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return location
+}
+
+func IsValidTimezone(timezone string) bool {
+	if timezone == "" || timezone == "Local" {
+		return false
+	}
+
+	_, err := time.LoadLocation(timezone)
+	return err == nil
+}
+
+func IsValidQuietHoursTime(value string) bool {
+	_, err := time.Parse(quietHoursLayout, value)
+	return err == nil
+}
+
+// GetQuietHours returns the user's quiet hours, filling in the defaults, with their timezone.
+func GetQuietHours(userId uint) (models.QuietHours, error) {
+	var user models.User
+	if err := database.DB.Select("id", "timezone").Where("id = ?", userId).First(&user).Error; err != nil {
+		return models.QuietHours{}, err
+	}
+
+	quietHours := models.QuietHours{UserID: userId, Start: defaultQuietHoursStart, End: defaultQuietHoursEnd}
+
+	err := database.DB.Where("user_id = ?", userId).First(&quietHours).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.QuietHours{}, err
+	}
+
+	quietHours.Timezone = user.Timezone
+	if quietHours.Timezone == "" {
+		quietHours.Timezone = DefaultTimezone
+	}
+
+	return quietHours, nil
+}
+
+// quietHoursEnd reports whether t falls in the quiet hours, and if so when they end.
+func quietHoursEnd(quietHours models.QuietHours, t time.Time) (time.Time, bool) {
+	if !quietHours.Enabled {
+		return time.Time{}, false
+	}
+
+	start, err := time.Parse(quietHoursLayout, quietHours.Start)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	end, err := time.Parse(quietHoursLayout, quietHours.End)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+	if startMinute == endMinute {
+		return time.Time{}, false
+	}
+
+	local := t.In(UserLocation(quietHours.Timezone))
+	minute := local.Hour()*60 + local.Minute()
+	endsAt := time.Date(local.Year(), local.Month(), local.Day(), end.Hour(), end.Minute(), 0, 0, local.Location())
+
+	if startMinute < endMinute {
+		return endsAt, minute >= startMinute && minute < endMinute
+	}
+
+	// The window spans midnight, e.g. 23:00 to 08:00
+	if minute >= startMinute {
+		return endsAt.AddDate(0, 0, 1), true
+	}
+
+	return endsAt, minute < endMinute
+}
+
+// deferPushForQuietHours holds the push back if the user is in their quiet hours, to be sent in the digest once they end.
+func deferPushForQuietHours(channel string, notification models.Notification) (bool, error) {
+	quietHours, err := GetQuietHours(notification.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	deliverAt, quiet := quietHoursEnd(quietHours, time.Now())
+	if !quiet {
+		return false, nil
+	}
+
+	return true, database.DB.Create(&models.DeferredPush{
+		UserID:     notification.UserID,
+		Channel:    channel,
+		Type:       notification.Type,
+		TargetType: notification.TargetType,
+		TargetID:   notification.TargetID,
+		Title:      notification.Title,
+		Message:    notification.Message,
+		Url:        notification.Url,
+		DeliverAt:  deliverAt,
+	}).Error
+}
+
+// QuietHoursDigest is the push sent for the pushes held back during quiet hours. A single one is sent as it was.
+func QuietHoursDigest(pushes []models.DeferredPush) models.Notification {
+	if len(pushes) == 1 {
+		return models.Notification{
+			UserID:     pushes[0].UserID,
+			Type:       pushes[0].Type,
+			TargetType: pushes[0].TargetType,
+			TargetID:   pushes[0].TargetID,
+			Title:      pushes[0].Title,
+			Message:    pushes[0].Message,
+			Url:        pushes[0].Url,
+		}
+	}
+
+	return models.Notification{
+		UserID:  pushes[0].UserID,
+		Type:    NotificationQuietHoursDigest,
+		Title:   "فنجون",
+		Message: fmt.Sprintf("تا وقتی استراحت می‌کردی %s اعلان تازه داشتی", utils.ToPersianDigits(fmt.Sprint(len(pushes)))),
+		Url:     "/notifications",
+	}
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/utils"
+	"gorm.io/gorm"
+)
+
+// PublishStory turns a draft or scheduled story into a published one, keeping its ID. Feeds are ordered by the
+// time it was published, so it takes its place at their top instead of where it was first written.
+func PublishStory(tx *gorm.DB, unpublished models.Story) (models.Story, error) {
+	var story models.Story
+
+	if err := tx.Model(&models.Story{}).Where("id = ?", unpublished.ID).Updates(map[string]any{
+		"status":       "published",
+		"published_at": time.Now(),
+	}).Error; err != nil {
+		return story, err
+	}
+
+	return story, tx.Preload("User").First(&story, unpublished.ID).Error
+}
+
+// NotifyMentionedUsers lets the users mentioned in a story or comment, the target, know about it.
+func NotifyMentionedUsers(sender models.User, text string, message string, url string, targetType string, targetId uint) {
+	usernames := utils.ExtractMentions(text)
+	if len(usernames) == 0 {
+		return
+	}
+
+	var mentionedUsers []models.User
+	if err := database.DB.Where("username IN ?", usernames).Find(&mentionedUsers).Error; err != nil {
+		fmt.Printf("Failed to find mentioned users: %v\n", err)
+		return
+	}
+
+	text = fmt.Sprintf(message, utils.GetUserDisplayName(sender))
+
+	for _, mentionedUser := range mentionedUsers {
+		if mentionedUser.ID == sender.ID {
+			continue
+		}
+
+		NotifyUser(models.Notification{
+			UserID:     mentionedUser.ID,
+			Type:       NotificationMentioned,
+			ActorID:    &sender.ID,
+			TargetType: targetType,
+			TargetID:   &targetId,
+			Title:      "بهت اشاره شد!",
+			Message:    text,
+			Url:        url,
+		})
+	}
+}
+
+// NotifyFollowers lets the followers of the story's author know it was published.
+func NotifyFollowers(story models.Story, message string, url string) {
+	author := story.User
+
+	var follows []models.Follow
+	if err := database.DB.Where("following_id = ?", author.ID).Find(&follows).Error; err != nil {
+		fmt.Printf("Failed to find followers: %v\n", err)
+		return
+	}
+
+	text := fmt.Sprintf(message, utils.GetUserDisplayName(author))
+
+	for _, follow := range follows {
+		NotifyUser(models.Notification{
+			UserID:     follow.FollowerID,
+			Type:       NotificationNewStory,
+			ActorID:    &author.ID,
+			TargetType: "story",
+			TargetID:   &story.ID,
+			Title:      "داستان تازه",
+			Message:    text,
+			Url:        url,
+		})
+	}
+}
+
+// NotifyContinuedAuthor lets the author of the parent story know someone continued it.
+func NotifyContinuedAuthor(story models.Story) {
+	if story.ParentID == nil || story.IsPrivate {
+		return
+	}
+
+	var parent models.Story
+	if err := database.DB.First(&parent, *story.ParentID).Error; err != nil {
+		return
+	}
+
+	if parent.UserID == story.UserID {
+		return
+	}
+
+	NotifyUser(models.Notification{
+		UserID:     parent.UserID,
+		Type:       NotificationStoryContinued,
+		ActorID:    &story.UserID,
+		TargetType: "story",
+		TargetID:   &story.ID,
+		Title:      "داستانت ادامه پیدا کرد!",
+		Message:    fmt.Sprintf("%s داستانت رو ادامه داد", utils.GetUserDisplayName(story.User)),
+		Url:        fmt.Sprintf("/story/%d", story.ID),
+	})
+}
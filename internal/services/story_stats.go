@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+)
+
+// StoryStatsDirtyKey is a Redis set of "<storyId>:<day>" members whose counters changed since the last flush.
+const StoryStatsDirtyKey = "story-stats:dirty"
+
+const StoryStatsDayLayout = "2006-01-02"
+
+// Counters are flushed every few minutes, they only need to outlive the day they count
+const storyStatsTTL = 48 * time.Hour
+
+func StoryStatsKey(kind string, storyId uint, day string) string {
+	return fmt.Sprintf("story-%s:%d:%s", kind, storyId, day)
+}
+
+// RecordStoryStats counts the viewer once per story and day in a HyperLogLog. kind is "views" or "impressions".
+func RecordStoryStats(kind string, storyIds []uint, viewer string) {
+	if len(storyIds) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+	day := time.Now().Format(StoryStatsDayLayout)
+
+	pipe := database.RedisClient.Pipeline()
+	for _, storyId := range storyIds {
+		key := StoryStatsKey(kind, storyId, day)
+		pipe.PFAdd(ctx, key, viewer)
+		pipe.Expire(ctx, key, storyStatsTTL)
+		pipe.SAdd(ctx, StoryStatsDirtyKey, fmt.Sprintf("%d:%s", storyId, day))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		fmt.Printf("Failed to record story %s: %v\n", kind, err)
+	}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"gorm.io/gorm"
+)
+
+// GrantPremium adds a subscription period for the user, queued after any period that is still running.
+func GrantPremium(tx *gorm.DB, userId uint, plan string, duration time.Duration, paymentId uint) (models.Subscription, error) {
+	startsAt := time.Now()
+
+	var latest models.Subscription
+	err := tx.Where("user_id = ? AND status = ? AND expires_at > ?", userId, "active", startsAt).Order("expires_at DESC").First(&latest).Error
+	if err == nil {
+		startsAt = latest.ExpiresAt
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.Subscription{}, err
+	}
+
+	subscription := models.Subscription{
+		UserID:    userId,
+		PaymentID: paymentId,
+		Plan:      plan,
+		Status:    "active",
+		StartsAt:  startsAt,
+		ExpiresAt: startsAt.Add(duration),
+	}
+
+	if err := tx.Create(&subscription).Error; err != nil {
+		return models.Subscription{}, err
+	}
+
+	if err := tx.Model(&models.User{}).Where("id = ?", userId).Update("is_premium", true).Error; err != nil {
+		return models.Subscription{}, err
+	}
+
+	return subscription, nil
+}
+
+// FailPayment marks a pending payment as failed and gives back the coupon use it reserved when it was created.
+func FailPayment(payment models.Payment) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Payment{}).Where("id = ? AND status = ?", payment.ID, "pending").Update("status", "failed")
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 || payment.CouponID == 0 {
+			return nil
+		}
+
+		released := tx.Where("coupon_id = ? AND payment_id = ?", payment.CouponID, payment.ID).Delete(&models.CouponRedemption{})
+		if released.Error != nil || released.RowsAffected == 0 {
+			return released.Error
+		}
+
+		return tx.Unscoped().Model(&models.Coupon{}).
+			Where("id = ? AND used_count > 0", payment.CouponID).
+			Update("used_count", gorm.Expr("used_count - 1")).Error
+	})
+}
@@ -0,0 +1,26 @@
+package services
+
+import "time"
+
+type SubscriptionPlan struct {
+	ID       string        `json:"id"`
+	Title    string        `json:"title"`
+	Price    int64         `json:"price"` // Toman
+	Duration time.Duration `json:"-"`
+	Days     int           `json:"days"`
+}
+
+var SubscriptionPlans = []SubscriptionPlan{
+	{ID: "monthly", Title: "اشتراک یک ماهه", Price: 99000, Duration: 30 * 24 * time.Hour, Days: 30},
+	{ID: "yearly", Title: "اشتراک یک ساله", Price: 990000, Duration: 365 * 24 * time.Hour, Days: 365},
+}
+
+func GetSubscriptionPlan(id string) (SubscriptionPlan, bool) {
+	for _, plan := range SubscriptionPlans {
+		if plan.ID == id {
+			return plan, true
+		}
+	}
+
+	return SubscriptionPlan{}, false
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Web Push messages are kept by the push service for a day if the browser is offline
+const webPushTTL = 24 * time.Hour
+
+// The whole payload goes in a single aes128gcm record
+const webPushRecordSize = 4096
+
+var webPushClient = &http.Client{Timeout: 15 * time.Second}
+
+// ErrWebPushDisabled is returned when no VAPID key is configured.
+var ErrWebPushDisabled = errors.New("web push is not configured, VAPID_PRIVATE_KEY is missing")
+
+type vapidKeys struct {
+	private   *ecdsa.PrivateKey
+	publicKey string // Uncompressed P-256 point, base64url, as browsers take it for applicationServerKey
+}
+
+var loadVapidKeys = sync.OnceValues(func() (*vapidKeys, error) {
+	encoded := os.Getenv("VAPID_PRIVATE_KEY")
+	if encoded == "" {
+		return nil, ErrWebPushDisabled
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID_PRIVATE_KEY: %w", err)
+	}
+
+	key, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID_PRIVATE_KEY: %w", err)
+	}
+
+	public := key.PublicKey().Bytes()
+
+	return &vapidKeys{
+		private: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(public[1:33]), Y: new(big.Int).SetBytes(public[33:])},
+			D:         new(big.Int).SetBytes(raw),
+		},
+		publicKey: base64.RawURLEncoding.EncodeToString(public),
+	}, nil
+})
+
+// GetVapidPublicKey returns the key browsers subscribe with.
+func GetVapidPublicKey() (string, error) {
+	keys, err := loadVapidKeys()
+	if err != nil {
+		return "", err
+	}
+
+	return keys.publicKey, nil
+}
+
+// vapidAuthorization signs the RFC 8292 header identifying us to the push service behind the endpoint.
+func vapidAuthorization(endpoint string) (string, error) {
+	keys, err := loadVapidKeys()
+	if err != nil {
+		return "", err
+	}
+
+	endpointUrl, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	subject := os.Getenv("VAPID_SUBJECT")
+	if subject == "" {
+		subject = "https://app.fenjoon.io"
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": endpointUrl.Scheme + "://" + endpointUrl.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": subject,
+	}).SignedString(keys.private)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("vapid t=%s, k=%s", token, keys.publicKey), nil
+}
+
+func hkdfBytes(secret []byte, salt []byte, info []byte, length int) ([]byte, error) {
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// EncryptWebPushPayload encrypts the payload for the subscription's browser as RFC 8291 describes, in the aes128gcm
+// content coding of RFC 8188.
+func EncryptWebPushPayload(subscription models.WebPushSubscription, payload []byte) ([]byte, error) {
+	serverKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return encryptWebPushPayload(subscription, payload, serverKey, salt)
+}
+
+// encryptWebPushPayload does the encryption with a given key pair and salt, which are fresh for every message.
+func encryptWebPushPayload(subscription models.WebPushSubscription, payload []byte, serverKey *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	p256dh, err := base64.RawURLEncoding.DecodeString(subscription.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh: %w", err)
+	}
+
+	authSecret, err := base64.RawURLEncoding.DecodeString(subscription.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth secret: %w", err)
+	}
+
+	userAgentKey, err := ecdh.P256().NewPublicKey(p256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh: %w", err)
+	}
+
+	sharedSecret, err := serverKey.ECDH(userAgentKey)
+	if err != nil {
+		return nil, err
+	}
+
+	serverPublic := serverKey.PublicKey().Bytes()
+
+	keyInfo := append([]byte("WebPush: info\x00"), p256dh...)
+	keyInfo = append(keyInfo, serverPublic...)
+
+	ikm, err := hkdfBytes(sharedSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	contentKey, err := hkdfBytes(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := hkdfBytes(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// 0x02 marks the last, and only, record
+	record := append(append([]byte{}, payload...), 0x02)
+	if len(record)+gcm.Overhead() > webPushRecordSize {
+		return nil, errors.New("web push payload is too large")
+	}
+
+	var body bytes.Buffer
+	body.Write(salt)
+	binary.Write(&body, binary.BigEndian, uint32(webPushRecordSize))
+	body.WriteByte(byte(len(serverPublic)))
+	body.Write(serverPublic)
+	body.Write(gcm.Seal(nil, nonce, record, nil))
+
+	return body.Bytes(), nil
+}
+
+// webPushHosts are the push services browsers subscribe with. Endpoints are only accepted on these hosts, anything
+// else would have the server post to an address of the caller's choosing.
+var webPushHosts = []string{
+	"fcm.googleapis.com",        // Chrome, Edge and other Chromium browsers
+	"android.googleapis.com",    // Older Chrome subscriptions
+	"push.services.mozilla.com", // Firefox, updates.push.services.mozilla.com
+	"push.apple.com",            // Safari, web.push.apple.com
+	"notify.windows.com",        // Legacy Edge, <region>.notify.windows.com
+}
+
+// IsAllowedWebPushEndpoint checks that the endpoint is an https URL of a known push service.
+func IsAllowedWebPushEndpoint(endpoint string) bool {
+	endpointUrl, err := url.Parse(endpoint)
+	if err != nil || endpointUrl.Scheme != "https" || endpointUrl.User != nil {
+		return false
+	}
+
+	if port := endpointUrl.Port(); port != "" && port != "443" {
+		return false
+	}
+
+	host := strings.ToLower(endpointUrl.Hostname())
+	for _, allowed := range webPushHosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// errWebPushGone means the browser unsubscribed, the subscription is removed.
+var errWebPushGone = errors.New("web push subscription is gone")
+
+func sendWebPush(subscription models.WebPushSubscription, payload []byte) error {
+	// Subscriptions saved before endpoints were checked are dropped rather than posted to
+	if !IsAllowedWebPushEndpoint(subscription.Endpoint) {
+		return errWebPushGone
+	}
+
+	body, err := EncryptWebPushPayload(subscription, payload)
+	if err != nil {
+		return err
+	}
+
+	authorization, err := vapidAuthorization(subscription.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", fmt.Sprint(int(webPushTTL.Seconds())))
+	req.Header.Set("Urgency", "high")
+	req.Header.Set("Authorization", authorization)
+
+	resp, err := webPushClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return errWebPushGone
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("web push failed with %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
+
+// SendWebPushNotification pushes the notification to every browser its user subscribed. The service worker shows
+// the title and body and opens the url.
+func SendWebPushNotification(notification models.Notification) error {
+	var subscriptions []models.WebPushSubscription
+	if err := database.DB.Where("user_id = ?", notification.UserID).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	title := notification.Title
+	if title == "" {
+		title = "فنجون"
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"title":      title,
+		"body":       notification.Message,
+		"url":        notification.Url,
+		"type":       notification.Type,
+		"targetType": notification.TargetType,
+		"targetId":   notification.TargetID,
+	})
+	if err != nil {
+		return err
+	}
+
+	var failed error
+	for _, subscription := range subscriptions {
+		err := sendWebPush(subscription, payload)
+		if errors.Is(err, errWebPushGone) {
+			if err := database.DB.Delete(&subscription).Error; err != nil {
+				fmt.Printf("Failed to remove web push subscription %d: %v\n", subscription.ID, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			failed = err
+		}
+	}
+
+	return failed
+}
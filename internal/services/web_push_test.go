@@ -0,0 +1,234 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Test vector of RFC 8291, Appendix A
+const (
+	rfc8291Plaintext        = "When I grow up, I want to be a watermelon"
+	rfc8291ServerPrivateKey = "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"
+	rfc8291UserAgentPrivate = "q1dXpw3UpT5VOmu_cf_v6ih07Aems3njxI-JWgLcM94"
+	rfc8291UserAgentPublic  = "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"
+	rfc8291AuthSecret       = "BTBZMqHH6r4Tts7J_aSIgg"
+	rfc8291Salt             = "DGv6ra1nlYgDCS1FRnbzlw"
+	rfc8291Body             = "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+)
+
+func decodeBase64Url(t *testing.T, value string) []byte {
+	t.Helper()
+
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		t.Fatalf("decoding %q: %v", value, err)
+	}
+
+	return decoded
+}
+
+func rfc8291Subscription(endpoint string) models.WebPushSubscription {
+	return models.WebPushSubscription{Endpoint: endpoint, P256dh: rfc8291UserAgentPublic, Auth: rfc8291AuthSecret}
+}
+
+// decryptWebPushPayload is what the browser does with a message, following RFC 8291 from the other side.
+func decryptWebPushPayload(t *testing.T, userAgentPrivate []byte, authSecret []byte, body []byte) []byte {
+	t.Helper()
+
+	if len(body) < 21 {
+		t.Fatalf("body too short: %d bytes", len(body))
+	}
+
+	salt := body[:16]
+	recordSize := binary.BigEndian.Uint32(body[16:20])
+	keyIdLength := int(body[20])
+	serverPublic := body[21 : 21+keyIdLength]
+	record := body[21+keyIdLength:]
+
+	if int(recordSize) < len(record) {
+		t.Fatalf("record of %d bytes exceeds the record size %d", len(record), recordSize)
+	}
+
+	userAgentKey, err := ecdh.P256().NewPrivateKey(userAgentPrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverKey, err := ecdh.P256().NewPublicKey(serverPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sharedSecret, err := userAgentKey.ECDH(serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyInfo := append([]byte("WebPush: info\x00"), userAgentKey.PublicKey().Bytes()...)
+	keyInfo = append(keyInfo, serverPublic...)
+
+	ikm, err := hkdfBytes(sharedSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contentKey, _ := hkdfBytes(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce, _ := hkdfBytes(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, record, nil)
+	if err != nil {
+		t.Fatalf("decrypting: %v", err)
+	}
+
+	// Strip the padding delimiter of the last record
+	end := bytes.LastIndexByte(plaintext, 0x02)
+	if end < 0 {
+		t.Fatal("missing last record delimiter")
+	}
+
+	return plaintext[:end]
+}
+
+func TestEncryptWebPushPayloadMatchesRFC8291(t *testing.T) {
+	serverKey, err := ecdh.P256().NewPrivateKey(decodeBase64Url(t, rfc8291ServerPrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := encryptWebPushPayload(rfc8291Subscription(""), []byte(rfc8291Plaintext), serverKey, decodeBase64Url(t, rfc8291Salt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := base64.RawURLEncoding.EncodeToString(body); got != rfc8291Body {
+		t.Fatalf("encrypted body\n got: %s\nwant: %s", got, rfc8291Body)
+	}
+}
+
+func TestEncryptWebPushPayloadRoundTrip(t *testing.T) {
+	body, err := EncryptWebPushPayload(rfc8291Subscription(""), []byte(rfc8291Plaintext))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := decryptWebPushPayload(t, decodeBase64Url(t, rfc8291UserAgentPrivate), decodeBase64Url(t, rfc8291AuthSecret), body)
+	if string(plaintext) != rfc8291Plaintext {
+		t.Fatalf("decrypted %q, want %q", plaintext, rfc8291Plaintext)
+	}
+}
+
+func TestIsAllowedWebPushEndpoint(t *testing.T) {
+	cases := map[string]bool{
+		"https://fcm.googleapis.com/fcm/send/abc":              true,
+		"https://updates.push.services.mozilla.com/wpush/v2/a": true,
+		"https://web.push.apple.com/QGx":                       true,
+		"https://wns2-by3p.notify.windows.com/w/?token=a":      true,
+		"https://fcm.googleapis.com:443/fcm/send/abc":          true,
+		"http://fcm.googleapis.com/fcm/send/abc":               false,
+		"https://fcm.googleapis.com:6379/fcm/send/abc":         false,
+		"https://user@fcm.googleapis.com/fcm/send/abc":         false,
+		"https://fcm.googleapis.com.evil.example/send":         false,
+		"https://evilfcm.googleapis.com.example/send":          false,
+		"https://localhost/send":                               false,
+		"https://127.0.0.1/send":                               false,
+		"https://10.0.0.1/send":                                false,
+		"http://localhost:6379":                                false,
+		"not a url":                                            false,
+	}
+
+	for endpoint, want := range cases {
+		if got := IsAllowedWebPushEndpoint(endpoint); got != want {
+			t.Errorf("IsAllowedWebPushEndpoint(%q) = %v, want %v", endpoint, got, want)
+		}
+	}
+}
+
+// TestSendWebPush posts to a mock push service standing in for FCM, which checks the VAPID header and decrypts the message.
+func TestSendWebPush(t *testing.T) {
+	t.Setenv("VAPID_PRIVATE_KEY", rfc8291ServerPrivateKey)
+	t.Setenv("VAPID_SUBJECT", "mailto:push@example.com")
+
+	keys, err := loadVapidKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received []byte
+	pushService := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Encoding") != "aes128gcm" || r.Header.Get("TTL") == "" {
+			t.Errorf("unexpected request: %s, headers %v", r.Method, r.Header)
+		}
+
+		token, publicKey, found := strings.Cut(strings.TrimPrefix(r.Header.Get("Authorization"), "vapid t="), ", k=")
+		if !found || publicKey != keys.publicKey {
+			t.Errorf("unexpected Authorization header %q", r.Header.Get("Authorization"))
+		}
+
+		public := decodeBase64Url(t, publicKey)
+		verifyKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(public[1:33]), Y: new(big.Int).SetBytes(public[33:])}
+
+		claims := jwt.MapClaims{}
+		if _, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) { return verifyKey, nil }, jwt.WithValidMethods([]string{"ES256"})); err != nil {
+			t.Errorf("invalid VAPID token: %v", err)
+		}
+
+		if claims["aud"] != "https://fcm.googleapis.com" || claims["sub"] != "mailto:push@example.com" {
+			t.Errorf("unexpected VAPID claims %v", claims)
+		}
+
+		received, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer pushService.Close()
+
+	// Requests to FCM reach the mock instead
+	transport := pushService.Client().Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig.ServerName = "example.com"
+	transport.DialContext = func(ctx context.Context, network string, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, pushService.Listener.Addr().String())
+	}
+
+	client := webPushClient
+	webPushClient = &http.Client{Transport: transport}
+	defer func() { webPushClient = client }()
+
+	if err := sendWebPush(rfc8291Subscription("https://fcm.googleapis.com/fcm/send/abc"), []byte(rfc8291Plaintext)); err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := decryptWebPushPayload(t, decodeBase64Url(t, rfc8291UserAgentPrivate), decodeBase64Url(t, rfc8291AuthSecret), received)
+	if string(plaintext) != rfc8291Plaintext {
+		t.Fatalf("push service received %q, want %q", plaintext, rfc8291Plaintext)
+	}
+
+	if err := sendWebPush(rfc8291Subscription("https://127.0.0.1/send"), []byte(rfc8291Plaintext)); err != errWebPushGone {
+		t.Fatalf("sending to a disallowed endpoint returned %v, want errWebPushGone", err)
+	}
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/messages"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/utils"
+)
+
+// The weekly digest goes out on Friday morning in each user's timezone
+const (
+	WeeklyDigestWeekday = time.Friday
+	WeeklyDigestHour    = 10
+)
+
+const (
+	weeklyDigestPeriod     = 7 * 24 * time.Hour
+	weeklyDigestTopStories = 3
+	weeklyDigestExcerpt    = 40
+)
+
+var weeklyDigestTemplate = template.Must(template.New("weekly-digest").Funcs(template.FuncMap{
+	"fa": func(value int64) string { return utils.ToPersianDigits(fmt.Sprint(value)) },
+}).Parse(messages.WeeklyDigestTemplate))
+
+type WeeklyDigestStory struct {
+	Excerpt string
+	Author  string
+}
+
+// WeeklyDigest is what happened around a user in the last week.
+type WeeklyDigest struct {
+	TopStories   []WeeklyDigestStory
+	Views        int64
+	Likes        int64
+	Comments     int64
+	NewFollowers int64
+	Url          string
+}
+
+func (d WeeklyDigest) IsEmpty() bool {
+	return len(d.TopStories) == 0 && d.Views == 0 && d.Likes == 0 && d.Comments == 0 && d.NewFollowers == 0
+}
+
+// Render writes the digest's notification message.
+func (d WeeklyDigest) Render() (string, error) {
+	var message strings.Builder
+	if err := weeklyDigestTemplate.Execute(&message, d); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(message.String()), nil
+}
+
+// BuildWeeklyDigest collects the top stories of the authors the user follows, how the user's own stories did
+// and their new followers since the given time.
+func BuildWeeklyDigest(userId uint, since time.Time) (WeeklyDigest, error) {
+	digest := WeeklyDigest{Url: "/profile"}
+
+	var stories []models.Story
+	if err := database.DB.Preload("User").
+		Joins("JOIN follows ON follows.following_id = stories.user_id AND follows.follower_id = ? AND follows.deleted_at IS NULL", userId).
+		Where("stories.status = ? AND stories.is_private = ? AND COALESCE(stories.published_at, stories.created_at) >= ?", "published", false, since).
+		Order("(SELECT COUNT(*) FROM likes WHERE likes.story_id = stories.id AND likes.deleted_at IS NULL) DESC, stories.id DESC").
+		Limit(weeklyDigestTopStories).
+		Find(&stories).Error; err != nil {
+		return digest, err
+	}
+
+	for _, story := range stories {
+		excerpt := []rune(strings.Join(strings.Fields(story.Text), " "))
+		if len(excerpt) > weeklyDigestExcerpt {
+			excerpt = append(excerpt[:weeklyDigestExcerpt], '…')
+		}
+
+		digest.TopStories = append(digest.TopStories, WeeklyDigestStory{Excerpt: string(excerpt), Author: utils.GetUserDisplayName(story.User)})
+	}
+
+	if len(stories) > 0 {
+		digest.Url = fmt.Sprintf("/story/%d", stories[0].ID)
+	}
+
+	if err := database.DB.Model(&models.StoryDailyStat{}).
+		Joins("JOIN stories ON stories.id = story_daily_stats.story_id").
+		Where("stories.user_id = ? AND story_daily_stats.date >= ?", userId, since.Format(StoryStatsDayLayout)).
+		Select("COALESCE(SUM(story_daily_stats.views), 0)").
+		Scan(&digest.Views).Error; err != nil {
+		return digest, err
+	}
+
+	if err := database.DB.Model(&models.Like{}).
+		Joins("JOIN stories ON stories.id = likes.story_id").
+		Where("stories.user_id = ? AND likes.user_id <> ? AND likes.created_at >= ?", userId, userId, since).
+		Count(&digest.Likes).Error; err != nil {
+		return digest, err
+	}
+
+	if err := database.DB.Model(&models.Comment{}).
+		Joins("JOIN stories ON stories.id = comments.story_id").
+		Where("stories.user_id = ? AND comments.user_id <> ? AND comments.created_at >= ?", userId, userId, since).
+		Count(&digest.Comments).Error; err != nil {
+		return digest, err
+	}
+
+	if err := database.DB.Model(&models.Follow{}).
+		Where("following_id = ? AND created_at >= ?", userId, since).
+		Count(&digest.NewFollowers).Error; err != nil {
+		return digest, err
+	}
+
+	return digest, nil
+}
+
+// SendWeeklyDigest queues the user's digest for this week, unless they turned it off, already got it or had a quiet week.
+// Users have no email address, so it goes out in-app and as a push.
+func SendWeeklyDigest(user models.User, now time.Time) error {
+	setting, err := getNotificationSetting(user.ID, NotificationWeeklyDigest)
+	if err != nil {
+		return err
+	}
+
+	if !setting.InApp && !setting.Push {
+		return nil
+	}
+
+	key := fmt.Sprintf("weekly-digest:%d:%s", user.ID, now.In(UserLocation(user.Timezone)).Format(StoryStatsDayLayout))
+	first, err := database.RedisClient.SetNX(context.Background(), key, 1, weeklyDigestPeriod+24*time.Hour).Result()
+	if err != nil || !first {
+		return err
+	}
+
+	if err := queueWeeklyDigest(user.ID, now); err != nil {
+		// Let the next run try again
+		database.RedisClient.Del(context.Background(), key)
+		return err
+	}
+
+	return nil
+}
+
+func queueWeeklyDigest(userId uint, now time.Time) error {
+	digest, err := BuildWeeklyDigest(userId, now.Add(-weeklyDigestPeriod))
+	if err != nil {
+		return err
+	}
+
+	if digest.IsEmpty() {
+		return nil
+	}
+
+	message, err := digest.Render()
+	if err != nil {
+		return err
+	}
+
+	return EnqueueNotification(database.DB, models.Notification{
+		UserID:  userId,
+		Type:    NotificationWeeklyDigest,
+		Title:   messages.WeeklyDigestTitle,
+		Message: message,
+		Url:     digest.Url,
+	})
+}
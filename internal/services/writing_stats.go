@@ -0,0 +1,74 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/models"
+)
+
+// GetWritingDays returns how many stories the user published on each day since the given time.
+func GetWritingDays(userId uint, since time.Time) (map[string]int64, error) {
+	var rows []struct {
+		Day   time.Time
+		Count int64
+	}
+
+	if err := database.DB.Model(&models.Story{}).
+		Select("DATE(COALESCE(published_at, created_at)) AS day, COUNT(*) AS count").
+		Where("user_id = ? AND status = ? AND COALESCE(published_at, created_at) >= ?", userId, "published", since).
+		Group("day").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	days := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		days[row.Day.Format(StoryStatsDayLayout)] = row.Count
+	}
+
+	return days, nil
+}
+
+// GetWritingStreaks returns the current and the longest run of consecutive writing days.
+// A streak stays current until a whole day passes without writing, so not having written yet today doesn't break it.
+func GetWritingStreaks(writingDays map[string]int64, today time.Time) (int, int) {
+	current := 0
+	day := today
+	if writingDays[day.Format(StoryStatsDayLayout)] == 0 {
+		day = day.AddDate(0, 0, -1)
+	}
+
+	for writingDays[day.Format(StoryStatsDayLayout)] > 0 {
+		current++
+		day = day.AddDate(0, 0, -1)
+	}
+
+	dates := make([]string, 0, len(writingDays))
+	for date := range writingDays {
+		dates = append(dates, date)
+	}
+
+	sort.Strings(dates)
+
+	longest, run := 0, 0
+	var previous time.Time
+	for _, date := range dates {
+		day, err := time.Parse(StoryStatsDayLayout, date)
+		if err != nil {
+			continue
+		}
+
+		if run > 0 && previous.AddDate(0, 0, 1).Equal(day) {
+			run++
+		} else {
+			run = 1
+		}
+
+		longest = max(longest, run)
+		previous = day
+	}
+
+	return current, longest
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type ZarinpalGateway struct {
+	MerchantID string
+	BaseUrl    string
+	client     *http.Client
+}
+
+type zarinpalResponse struct {
+	Data struct {
+		Code      int    `json:"code"`
+		Message   string `json:"message"`
+		Authority string `json:"authority"`
+		RefID     int64  `json:"ref_id"`
+	} `json:"data"`
+	Errors any `json:"errors"`
+}
+
+func NewZarinpalGateway(merchantId string, sandbox bool) *ZarinpalGateway {
+	baseUrl := "https://payment.zarinpal.com"
+	if sandbox {
+		baseUrl = "https://sandbox.zarinpal.com"
+	}
+
+	return &ZarinpalGateway{MerchantID: merchantId, BaseUrl: baseUrl, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+func (g *ZarinpalGateway) Name() string {
+	return "zarinpal"
+}
+
+func (g *ZarinpalGateway) RequestPayment(amount int64, description string, callbackUrl string) (string, string, error) {
+	body := map[string]any{
+		"merchant_id":  g.MerchantID,
+		"amount":       amount,
+		"currency":     "IRT",
+		"description":  description,
+		"callback_url": callbackUrl,
+	}
+
+	var result zarinpalResponse
+	if err := g.post("/pg/v4/payment/request.json", body, &result); err != nil {
+		return "", "", err
+	}
+
+	if result.Data.Code != 100 || result.Data.Authority == "" {
+		return "", "", fmt.Errorf("zarinpal payment request failed: code %d, errors %v", result.Data.Code, result.Errors)
+	}
+
+	return result.Data.Authority, fmt.Sprintf("%s/pg/StartPay/%s", g.BaseUrl, result.Data.Authority), nil
+}
+
+func (g *ZarinpalGateway) VerifyPayment(authority string, amount int64) (string, error) {
+	body := map[string]any{
+		"merchant_id": g.MerchantID,
+		"amount":      amount,
+		"authority":   authority,
+	}
+
+	var result zarinpalResponse
+	if err := g.post("/pg/v4/payment/verify.json", body, &result); err != nil {
+		return "", err
+	}
+
+	// 101 means the payment has already been verified before
+	if result.Data.Code != 100 && result.Data.Code != 101 {
+		return "", ErrPaymentNotVerified
+	}
+
+	return fmt.Sprintf("%d", result.Data.RefID), nil
+}
+
+func (g *ZarinpalGateway) post(path string, body any, result any) error {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", g.BaseUrl+path, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
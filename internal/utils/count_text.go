@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+const zeroWidthNonJoiner = '\u200c'
+
+// CountWords counts words the way Persian readers do: the zero-width non-joiner keeps
+// "می‌روم" a single word, while spaces and punctuation such as "،" and "؟" separate words.
+func CountWords(text string) int {
+	return len(strings.FieldsFunc(text, func(r rune) bool {
+		return r != zeroWidthNonJoiner && (unicode.IsSpace(r) || unicode.IsPunct(r))
+	}))
+}
+
+// CountCharacters counts visible characters, skipping whitespace, zero-width joiners and diacritics (اِعراب).
+func CountCharacters(text string) int {
+	count := 0
+	for _, r := range text {
+		if unicode.IsSpace(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Cf, r) {
+			continue
+		}
+
+		count++
+	}
+
+	return count
+}
@@ -0,0 +1,22 @@
+package utils
+
+import "regexp"
+
+var mentionRegex = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z][A-Za-z0-9_]{2,29})\b`)
+
+func ExtractMentions(text string) []string {
+	seen := map[string]bool{}
+	mentions := []string{}
+
+	for _, match := range mentionRegex.FindAllStringSubmatch(text, -1) {
+		username := NormalizeUsername(match[1])
+		if seen[username] {
+			continue
+		}
+
+		seen[username] = true
+		mentions = append(mentions, username)
+	}
+
+	return mentions
+}
@@ -19,5 +19,9 @@ func GetUserDisplayName(user models.User) string {
 		return user.FirstName + user.LastName
 	}
 
+	if user.Username != "" {
+		return "@" + user.Username
+	}
+
 	return fmt.Sprintf("کاربر %d#", user.ID)
 }
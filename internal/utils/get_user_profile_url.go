@@ -0,0 +1,15 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/freakingeek/fenjoon/internal/models"
+)
+
+func GetUserProfileUrl(user models.User) string {
+	if user.Username != "" {
+		return fmt.Sprintf("/@%s", user.Username)
+	}
+
+	return fmt.Sprintf("/author/%d", user.ID)
+}
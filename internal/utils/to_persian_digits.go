@@ -0,0 +1,9 @@
+package utils
+
+import "strings"
+
+var persianDigitsReplacer = strings.NewReplacer("0", "۰", "1", "۱", "2", "۲", "3", "۳", "4", "۴", "5", "۵", "6", "۶", "7", "۷", "8", "۸", "9", "۹")
+
+func ToPersianDigits(text string) string {
+	return persianDigitsReplacer.Replace(text)
+}
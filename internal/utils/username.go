@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Usernames are stored lowercased so lookups and uniqueness checks are case-insensitive.
+var usernameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]{2,29}$`)
+
+var reservedUsernames = map[string]bool{
+	"admin":         true,
+	"administrator": true,
+	"api":           true,
+	"app":           true,
+	"author":        true,
+	"bot":           true,
+	"fenjoon":       true,
+	"fnjo":          true,
+	"help":          true,
+	"login":         true,
+	"logout":        true,
+	"me":            true,
+	"moderator":     true,
+	"notifications": true,
+	"root":          true,
+	"settings":      true,
+	"signup":        true,
+	"story":         true,
+	"stories":       true,
+	"support":       true,
+	"system":        true,
+	"user":          true,
+	"users":         true,
+}
+
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(username), "@"))
+}
+
+func IsValidUsername(username string) bool {
+	return usernameRegex.MatchString(username) && !strings.Contains(username, "__")
+}
+
+func IsReservedUsername(username string) bool {
+	return reservedUsernames[username]
+}
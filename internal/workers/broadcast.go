@@ -0,0 +1,38 @@
+package workers
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/services"
+)
+
+const broadcastInterval = 10 * time.Second
+
+func RunBroadcastWorker() {
+	runEvery("broadcasts", broadcastInterval, queueDueBroadcasts)
+}
+
+// queueDueBroadcasts queues the next batch of every due broadcast, sized by its rate so the push proxy isn't flooded.
+func queueDueBroadcasts() error {
+	var broadcasts []models.Broadcast
+	if err := database.DB.
+		Where("status IN ? AND scheduled_at <= ?", []string{"scheduled", "sending"}, time.Now()).
+		Order("scheduled_at ASC").
+		Find(&broadcasts).Error; err != nil {
+		return err
+	}
+
+	for _, broadcast := range broadcasts {
+		limit := max(1, broadcast.RatePerMinute*int(broadcastInterval/time.Second)/60)
+
+		if err := services.QueueBroadcastBatch(broadcast, limit); err != nil && !errors.Is(err, services.ErrBroadcastCancelled) {
+			log.Printf("Worker broadcasts failed to queue broadcast %d: %v", broadcast.ID, err)
+		}
+	}
+
+	return nil
+}
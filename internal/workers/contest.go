@@ -0,0 +1,90 @@
+package workers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/services"
+	"gorm.io/gorm"
+)
+
+func RunContestWorker() {
+	runEvery("contests", 5*time.Minute, announceContestResults)
+}
+
+func announceContestResults() error {
+	var contests []models.Contest
+	if err := database.DB.
+		Where("type = ? AND results_announced_at IS NULL AND voting_ends_at <= ?", "contest", time.Now()).
+		Find(&contests).Error; err != nil {
+		return err
+	}
+
+	for _, contest := range contests {
+		var entries []models.ContestEntry
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			// Entries whose story was deleted or made private since are left out of the ranking
+			if err := tx.Model(&models.ContestEntry{}).Where("contest_id = ?", contest.ID).
+				Update("votes_count", gorm.Expr("(SELECT COUNT(*) FROM contest_votes WHERE contest_votes.entry_id = contest_entries.id)")).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Where("contest_id = ?", contest.ID).
+				Where("story_id IN (?)", tx.Model(&models.Story{}).Where("status = ? AND is_private = ?", "published", false).Select("id")).
+				Order("votes_count DESC, created_at ASC").
+				Find(&entries).Error; err != nil {
+				return err
+			}
+
+			for i := range entries {
+				if i >= contest.WinnersCount || entries[i].VotesCount == 0 {
+					break
+				}
+
+				entries[i].Rank = i + 1
+				if err := tx.Model(&entries[i]).Update("rank", entries[i].Rank).Error; err != nil {
+					return err
+				}
+			}
+
+			return tx.Model(&contest).Update("results_announced_at", time.Now()).Error
+		})
+
+		if err != nil {
+			return err
+		}
+
+		url := fmt.Sprintf("/contests/%d", contest.ID)
+
+		for _, entry := range entries {
+			if entry.Rank == 0 {
+				services.NotifyUser(models.Notification{
+					UserID:     entry.UserID,
+					Type:       services.NotificationContestResult,
+					TargetType: "contest",
+					TargetID:   &contest.ID,
+					Title:      "نتایج مسابقه اعلام شد",
+					Message:    fmt.Sprintf("نتایج مسابقه «%s» اعلام شد، ممنون که شرکت کردی", contest.Title),
+					Url:        url,
+				})
+				continue
+			}
+
+			services.NotifyUser(models.Notification{
+				UserID:     entry.UserID,
+				Type:       services.NotificationContestResult,
+				TargetType: "contest",
+				TargetID:   &contest.ID,
+				Title:      "تبریک، برنده شدی!",
+				Message:    fmt.Sprintf("داستانت توی مسابقه «%s» رتبه %d رو گرفت", contest.Title, entry.Rank),
+				Url:        url,
+			})
+
+			services.AwardBadge(entry.UserID, "contest_winner")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package workers
+
+import (
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/services"
+	"gorm.io/gorm"
+)
+
+const deferredPushBatchSize = 1000
+
+func RunDeferredPushWorker() {
+	runEvery("deferred-pushes", time.Minute, sendQuietHoursDigests)
+}
+
+// sendQuietHoursDigests queues one digest per user and channel for the pushes held back until their quiet hours ended.
+func sendQuietHoursDigests() error {
+	for {
+		var pushes []models.DeferredPush
+		if err := database.DB.Where("deliver_at <= ?", time.Now()).Order("user_id, channel, id").Limit(deferredPushBatchSize).Find(&pushes).Error; err != nil {
+			return err
+		}
+
+		if len(pushes) == 0 {
+			return nil
+		}
+
+		// A user's pushes cut off at the end of the batch are left for the next round, unless they fill it alone
+		full := len(pushes) == deferredPushBatchSize
+		last := pushes[len(pushes)-1]
+		if full && pushes[0].UserID != last.UserID {
+			for len(pushes) > 0 && pushes[len(pushes)-1].UserID == last.UserID {
+				pushes = pushes[:len(pushes)-1]
+			}
+		}
+
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			ids := make([]uint, len(pushes))
+			for i, push := range pushes {
+				ids[i] = push.ID
+			}
+
+			for start := 0; start < len(pushes); {
+				end := start
+				for end < len(pushes) && pushes[end].UserID == pushes[start].UserID && pushes[end].Channel == pushes[start].Channel {
+					end++
+				}
+
+				if err := services.EnqueueNotificationOn(tx, services.QuietHoursDigest(pushes[start:end]), pushes[start].Channel); err != nil {
+					return err
+				}
+
+				start = end
+			}
+
+			return tx.Delete(&models.DeferredPush{}, ids).Error
+		})
+		if err != nil {
+			return err
+		}
+
+		if !full {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package workers
+
+import (
+	"log"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/services"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	notificationWorkers      = 8
+	notificationBatchSize    = 100
+	notificationPollInterval = time.Second
+	// A job not finished within its lease, e.g. because the worker died, is picked up again
+	notificationJobLease = 5 * time.Minute
+	// Delivered jobs are kept a while for debugging
+	deliveredNotificationJobsTTL = 7 * 24 * time.Hour
+	// Notifications untouched for this long are removed for good, a batch at a time so the table isn't locked for long
+	notificationRetention           = 90 * 24 * time.Hour
+	expiredNotificationsDeleteBatch = 5000
+)
+
+// RunNotificationWorker delivers queued notification jobs with a pool of workers. Any number of
+// processes can run it side by side, each claims its own jobs.
+func RunNotificationWorker() {
+	go runEvery("notification-jobs-cleanup", time.Hour, deleteDeliveredNotificationJobs)
+	go runEvery("notifications-retention", time.Hour, deleteExpiredNotifications)
+
+	jobs := make(chan models.NotificationJob)
+
+	for i := 0; i < notificationWorkers; i++ {
+		go func() {
+			for job := range jobs {
+				processNotificationJob(job)
+			}
+		}()
+	}
+
+	for {
+		claimed, err := claimNotificationJobs()
+		if err != nil {
+			log.Printf("Worker notifications failed to claim jobs: %v", err)
+		}
+
+		// Blocks until a worker is free, so no more is claimed than the pool keeps up with
+		for _, job := range claimed {
+			jobs <- job
+		}
+
+		if len(claimed) < notificationBatchSize {
+			time.Sleep(notificationPollInterval)
+		}
+	}
+}
+
+// claimNotificationJobs leases a batch of due jobs. Rows locked by other workers are skipped rather than waited on.
+func claimNotificationJobs() ([]models.NotificationJob, error) {
+	var jobs []models.NotificationJob
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND next_attempt_at <= ?) OR (status = ? AND locked_until < ?)", "pending", now, "processing", now).
+			Order("next_attempt_at ASC").
+			Limit(notificationBatchSize).
+			Find(&jobs).Error; err != nil {
+			return err
+		}
+
+		if len(jobs) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(jobs))
+		for i := range jobs {
+			ids[i] = jobs[i].ID
+		}
+
+		return tx.Model(&models.NotificationJob{}).Where("id IN ?", ids).Updates(map[string]any{
+			"status":       "processing",
+			"locked_until": now.Add(notificationJobLease),
+		}).Error
+	})
+
+	return jobs, err
+}
+
+func processNotificationJob(job models.NotificationJob) {
+	deliveryErr := services.DeliverNotificationJob(job)
+	now := time.Now()
+
+	if deliveryErr == nil {
+		recordNotificationOutcome(job, "delivered")
+
+		if err := database.DB.Model(&job).Updates(map[string]any{"status": "delivered", "delivered_at": now, "locked_until": nil}).Error; err != nil {
+			log.Printf("Worker notifications failed to mark job %d delivered: %v", job.ID, err)
+		}
+		return
+	}
+
+	attempts := job.Attempts + 1
+	updates := map[string]any{
+		"status":          "pending",
+		"attempts":        attempts,
+		"last_error":      deliveryErr.Error(),
+		"next_attempt_at": now.Add(services.NotificationRetryDelay(attempts)),
+		"locked_until":    nil,
+	}
+
+	outcome := "failed"
+	if attempts >= services.NotificationJobMaxAttempts {
+		updates["status"] = "dead"
+		outcome = "dead"
+	}
+
+	recordNotificationOutcome(job, outcome)
+	log.Printf("Worker notifications failed to deliver job %d on %s (attempt %d): %v", job.ID, job.Channel, attempts, deliveryErr)
+
+	if err := database.DB.Model(&job).Updates(updates).Error; err != nil {
+		log.Printf("Worker notifications failed to reschedule job %d: %v", job.ID, err)
+	}
+}
+
+func recordNotificationOutcome(job models.NotificationJob, outcome string) {
+	services.RecordNotificationOutcome(job.Channel, outcome)
+
+	if job.BroadcastID != nil {
+		services.RecordBroadcastOutcome(*job.BroadcastID, job.Channel, outcome)
+	}
+}
+
+func deleteDeliveredNotificationJobs() error {
+	return database.DB.Where("status = ? AND delivered_at < ?", "delivered", time.Now().Add(-deliveredNotificationJobsTTL)).Delete(&models.NotificationJob{}).Error
+}
+
+// deleteExpiredNotifications removes notifications past the retention period, with their grouped actors.
+func deleteExpiredNotifications() error {
+	cutoff := time.Now().Add(-notificationRetention)
+
+	for {
+		var ids []uint
+		if err := database.DB.Unscoped().Model(&models.Notification{}).
+			Where("COALESCE(last_actor_at, created_at) < ?", cutoff).
+			Limit(expiredNotificationsDeleteBatch).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("notification_id IN ?", ids).Delete(&models.NotificationActor{}).Error; err != nil {
+				return err
+			}
+
+			return tx.Unscoped().Delete(&models.Notification{}, ids).Error
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(ids) < expiredNotificationsDeleteBatch {
+			return nil
+		}
+	}
+}
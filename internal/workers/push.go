@@ -0,0 +1,69 @@
+package workers
+
+import (
+	"log"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/services"
+)
+
+const (
+	// Expo recommends waiting a while before asking for receipts, and keeps them for a day
+	pushReceiptDelay = 15 * time.Minute
+	pushReceiptTTL   = 24 * time.Hour
+)
+
+func RunPushReceiptWorker() {
+	runEvery("push-receipts", 15*time.Minute, checkPushReceipts)
+}
+
+// checkPushReceipts removes the tokens of devices Expo reports as no longer registered.
+func checkPushReceipts() error {
+	// Receipts expired on Expo's side can't be checked anymore
+	if err := database.DB.Where("created_at < ?", time.Now().Add(-pushReceiptTTL)).Delete(&models.PushTicket{}).Error; err != nil {
+		return err
+	}
+
+	var tickets []models.PushTicket
+	if err := database.DB.Where("created_at < ?", time.Now().Add(-pushReceiptDelay)).Find(&tickets).Error; err != nil {
+		return err
+	}
+
+	if len(tickets) == 0 {
+		return nil
+	}
+
+	ticketIds := make([]string, len(tickets))
+	for i, ticket := range tickets {
+		ticketIds[i] = ticket.TicketID
+	}
+
+	receipts, err := services.GetPushReceipts(ticketIds)
+	if err != nil {
+		return err
+	}
+
+	var checked []uint
+	for _, ticket := range tickets {
+		receipt, ok := receipts[ticket.TicketID]
+		if !ok {
+			continue
+		}
+
+		if receipt.Details.Error == services.ExpoDeviceNotRegistered {
+			services.RemovePushToken(ticket.PushTokenID)
+		} else if receipt.Status == "error" {
+			log.Printf("Push to token %d failed: %s", ticket.PushTokenID, receipt.Message)
+		}
+
+		checked = append(checked, ticket.ID)
+	}
+
+	if len(checked) == 0 {
+		return nil
+	}
+
+	return database.DB.Delete(&models.PushTicket{}, checked).Error
+}
@@ -0,0 +1,70 @@
+package workers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/services"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func RunStoryScheduler() {
+	runEvery("story-scheduler", time.Minute, publishDueStories)
+}
+
+func publishDueStories() error {
+	var stories []models.Story
+	if err := database.DB.Where("status = ? AND publish_at <= ?", "scheduled", time.Now()).Order("publish_at ASC").Find(&stories).Error; err != nil {
+		return err
+	}
+
+	for _, scheduled := range stories {
+		var story models.Story
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			// The author may have rescheduled or canceled it since it was listed
+			var due models.Story
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id = ? AND status = ? AND publish_at <= ?", scheduled.ID, "scheduled", time.Now()).
+				First(&due).Error; err != nil {
+				return err
+			}
+
+			published, err := services.PublishStory(tx, due)
+			story = published
+			return err
+		})
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		url := fmt.Sprintf("/story/%d", story.ID)
+
+		services.NotifyUser(models.Notification{
+			UserID:     story.UserID,
+			Type:       services.NotificationStoryPublished,
+			TargetType: "story",
+			TargetID:   &story.ID,
+			Title:      "داستانت منتشر شد",
+			Message:    "داستان زمان‌بندی‌شده‌ات همین الان منتشر شد",
+			Url:        url,
+		})
+		services.NotifyMentionedUsers(story.User, story.Text, "%s توی داستانش بهت اشاره کرد", url, "story", story.ID)
+		services.NotifyContinuedAuthor(story)
+		services.EvaluateBadges(services.BadgeEventStoryCreated, story.UserID)
+
+		if !story.IsPrivate {
+			services.NotifyFollowers(story, "%s داستان تازه‌ای منتشر کرد", url)
+		}
+	}
+
+	return nil
+}
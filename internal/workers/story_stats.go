@@ -0,0 +1,70 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/services"
+	"gorm.io/gorm/clause"
+)
+
+func RunStoryStatsWorker() {
+	runEvery("story-stats", 5*time.Minute, flushStoryStats)
+}
+
+// flushStoryStats copies the Redis view and impression counters of changed stories into Postgres.
+func flushStoryStats() error {
+	ctx := context.Background()
+
+	members, err := database.RedisClient.SMembers(ctx, services.StoryStatsDirtyKey).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		// Removed before counting, so views recorded meanwhile mark it dirty again for the next flush
+		if err := database.RedisClient.SRem(ctx, services.StoryStatsDirtyKey, member).Err(); err != nil {
+			return err
+		}
+
+		storyIdPart, day, ok := strings.Cut(member, ":")
+		if !ok {
+			continue
+		}
+
+		storyId, err := strconv.ParseUint(storyIdPart, 10, 32)
+		if err != nil {
+			continue
+		}
+
+		date, err := time.Parse(services.StoryStatsDayLayout, day)
+		if err != nil {
+			continue
+		}
+
+		views, err := database.RedisClient.PFCount(ctx, services.StoryStatsKey("views", uint(storyId), day)).Result()
+		if err != nil {
+			return err
+		}
+
+		impressions, err := database.RedisClient.PFCount(ctx, services.StoryStatsKey("impressions", uint(storyId), day)).Result()
+		if err != nil {
+			return err
+		}
+
+		stat := models.StoryDailyStat{StoryID: uint(storyId), Date: date, Views: views, Impressions: impressions}
+		if err := database.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "story_id"}, {Name: "date"}},
+			DoUpdates: clause.AssignmentColumns([]string{"views", "impressions", "updated_at"}),
+		}).Create(&stat).Error; err != nil {
+			return fmt.Errorf("story %d on %s: %w", storyId, day, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package workers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/services"
+)
+
+const subscriptionReminderWindow = 3 * 24 * time.Hour
+
+// Payments still pending after this are abandoned at the gateway, which refunds them if they were ever paid
+const pendingPaymentTimeout = time.Hour
+
+func RunSubscriptionWorker() {
+	runEvery("subscriptions", 10*time.Minute, func() error {
+		if err := expireSubscriptions(); err != nil {
+			return err
+		}
+
+		if err := expirePendingPayments(); err != nil {
+			return err
+		}
+
+		return sendRenewalReminders()
+	})
+}
+
+func expireSubscriptions() error {
+	var subscriptions []models.Subscription
+	if err := database.DB.Where("status = ? AND expires_at <= ?", "active", time.Now()).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		if err := database.DB.Model(&subscription).Update("status", "expired").Error; err != nil {
+			return err
+		}
+
+		var remaining int64
+		if err := database.DB.Model(&models.Subscription{}).
+			Where("user_id = ? AND status = ? AND expires_at > ?", subscription.UserID, "active", time.Now()).
+			Count(&remaining).Error; err != nil {
+			return err
+		}
+
+		if remaining > 0 {
+			continue
+		}
+
+		if err := database.DB.Model(&models.User{}).Where("id = ?", subscription.UserID).Update("is_premium", false).Error; err != nil {
+			return err
+		}
+
+		services.NotifyUser(models.Notification{
+			UserID:  subscription.UserID,
+			Type:    services.NotificationSubscriptionExpiry,
+			Title:   "اشتراکت تموم شد",
+			Message: "اشتراک حرفه‌ای فنجون تموم شد. برای استفاده دوباره از امکانات حرفه‌ای، اشتراکت رو تمدید کن",
+			Url:     "/premium",
+		})
+	}
+
+	return nil
+}
+
+// expirePendingPayments fails the payments the buyer never came back from, freeing the coupon uses they reserved.
+func expirePendingPayments() error {
+	var payments []models.Payment
+	if err := database.DB.Where("status = ? AND created_at <= ?", "pending", time.Now().Add(-pendingPaymentTimeout)).Find(&payments).Error; err != nil {
+		return err
+	}
+
+	for _, payment := range payments {
+		if err := services.FailPayment(payment); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sendRenewalReminders() error {
+	now := time.Now()
+
+	var subscriptions []models.Subscription
+	if err := database.DB.
+		Where("status = ? AND reminder_sent_at IS NULL", "active").
+		Where("expires_at > ? AND expires_at <= ?", now, now.Add(subscriptionReminderWindow)).
+		Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		// Skip users who already renewed; the queued period starts when this one ends
+		var renewed int64
+		if err := database.DB.Model(&models.Subscription{}).
+			Where("user_id = ? AND status = ? AND starts_at >= ?", subscription.UserID, "active", subscription.ExpiresAt).
+			Count(&renewed).Error; err != nil {
+			return err
+		}
+
+		if err := database.DB.Model(&subscription).Update("reminder_sent_at", now).Error; err != nil {
+			return err
+		}
+
+		if renewed > 0 {
+			continue
+		}
+
+		days := int(subscription.ExpiresAt.Sub(now).Hours()/24) + 1
+
+		services.NotifyUser(models.Notification{
+			UserID:  subscription.UserID,
+			Type:    services.NotificationSubscriptionExpiry,
+			Title:   "اشتراکت رو به پایانه",
+			Message: fmt.Sprintf("فقط %d روز از اشتراک حرفه‌ای‌ات باقی مونده. برای از دست ندادن امکانات، تمدیدش کن", days),
+			Url:     "/premium",
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package workers
+
+import (
+	"log"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+	"github.com/freakingeek/fenjoon/internal/models"
+	"github.com/freakingeek/fenjoon/internal/services"
+)
+
+const weeklyDigestBatchSize = 500
+
+func RunWeeklyDigestWorker() {
+	runEvery("weekly-digest", time.Hour, sendWeeklyDigests)
+}
+
+// sendWeeklyDigests sends the digest to the users whose local time just reached the digest hour.
+func sendWeeklyDigests() error {
+	var timezones []string
+	if err := database.DB.Model(&models.User{}).Distinct("timezone").Pluck("timezone", &timezones).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	for _, timezone := range timezones {
+		local := now.In(services.UserLocation(timezone))
+		if local.Weekday() != services.WeeklyDigestWeekday || local.Hour() != services.WeeklyDigestHour {
+			continue
+		}
+
+		var lastId uint
+		for {
+			var users []models.User
+			if err := database.DB.Select("id", "timezone").
+				Where("timezone = ? AND is_bot = ? AND id > ?", timezone, false, lastId).
+				Order("id ASC").
+				Limit(weeklyDigestBatchSize).
+				Find(&users).Error; err != nil {
+				return err
+			}
+
+			for _, user := range users {
+				if err := services.SendWeeklyDigest(user, now); err != nil {
+					log.Printf("Worker weekly-digest failed for user %d: %v", user.ID, err)
+				}
+			}
+
+			if len(users) < weeklyDigestBatchSize {
+				break
+			}
+
+			lastId = users[len(users)-1].ID
+		}
+	}
+
+	return nil
+}
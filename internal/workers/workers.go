@@ -0,0 +1,28 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/freakingeek/fenjoon/internal/database"
+)
+
+// runEvery calls job on every tick. A Redis lock makes sure only one API instance runs a given job per tick.
+func runEvery(name string, interval time.Duration, job func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		acquired, err := database.RedisClient.SetNX(context.Background(), "worker-lock:"+name, 1, interval/2).Result()
+		if err != nil {
+			log.Printf("Worker %s failed to acquire lock: %v", name, err)
+		} else if acquired {
+			if err := job(); err != nil {
+				log.Printf("Worker %s failed: %v", name, err)
+			}
+		}
+
+		<-ticker.C
+	}
+}